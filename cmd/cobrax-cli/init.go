@@ -51,15 +51,11 @@ This command will create a new directory with the following structure:
 		slog.SetDefault(l)
 		cobrax.SetLogger(l)
 
-		// Read config file
-		opts := []cobrax.ConfigOption{cobrax.WithConfigFileFlag(cmd, "config"), cobrax.WithOverrideBy(cmd.Name())}
+		// Read config file and bind flags (flags of the command to be executed)
+		opts := []cobrax.ConfigOption{cobrax.WithConfigFileFlag(cmd, "config"), cobrax.WithOverrideByCommand(cmd), cobrax.WithFs(fs), cobrax.WithFlags(cmd.Flags())}
 		if err := cobrax.BindConfigs(v, cmd.Root().Name(), opts...); err != nil {
 			return err
 		}
-		// Bind flags (flags of the command to be executed)
-		if err := v.BindPFlags(cmd.Flags()); err != nil {
-			return err
-		}
 
 		slog.Debug("bind flags and config values")
 		slog.Debug(cobrax.DebugViper(v))
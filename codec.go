@@ -0,0 +1,307 @@
+package cobrax
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// ConfigCodec decodes and encodes config files of a format.
+type ConfigCodec interface {
+	Encode(m map[string]any) ([]byte, error)
+	Decode(b []byte, m map[string]any) error
+}
+
+var configCodecs = struct {
+	sync.RWMutex
+	codecs map[string]ConfigCodec
+	exts   []string
+}{codecs: make(map[string]ConfigCodec)}
+
+func init() {
+	RegisterConfigCodec(viperCodec{format: "ini"}, "ini")
+	RegisterConfigCodec(viperCodec{format: "hcl"}, "hcl")
+	RegisterConfigCodec(viperCodec{format: "dotenv"}, "env", "dotenv")
+	RegisterConfigCodec(viperCodec{format: "properties"}, "properties", "props", "prop")
+	RegisterConfigCodec(jsoncCodec{}, "jsonc", "json5")
+}
+
+// RegisterConfigCodec registers the codec for the config file extensions.
+// Files with the extensions are discovered next to the json, toml and yaml files,
+// and the extensions can be used as the format of PrintConfig and the config read from stdin.
+// Registering an extension again replaces its codec.
+func RegisterConfigCodec(codec ConfigCodec, exts ...string) {
+	configCodecs.Lock()
+	defer configCodecs.Unlock()
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+		if _, ok := configCodecs.codecs[ext]; !ok {
+			configCodecs.exts = append(configCodecs.exts, ext)
+		}
+		configCodecs.codecs[ext] = codec
+	}
+}
+
+func lookupConfigCodec(ext string) (ConfigCodec, bool) {
+	configCodecs.RLock()
+	defer configCodecs.RUnlock()
+	codec, ok := configCodecs.codecs[strings.ToLower(ext)]
+	return codec, ok
+}
+
+// configCodecExts returns the extensions of the registered codecs in the order of registration.
+func configCodecExts() []string {
+	configCodecs.RLock()
+	defer configCodecs.RUnlock()
+	return slices.Clone(configCodecs.exts)
+}
+
+// decodeConfig decodes the config in the format, which is the extension of the config file.
+func decodeConfig(format string, b []byte) (map[string]any, error) {
+	if codec, ok := lookupConfigCodec(format); ok {
+		m := make(map[string]any)
+		if err := codec.Decode(b, m); err != nil {
+			return nil, newConfigSyntaxError(err, b)
+		}
+		return lowerConfigKeys(m), nil
+	}
+	if !slices.Contains(viper.SupportedExts, format) {
+		return nil, fmt.Errorf("unsupported config format: %q", format)
+	}
+	fv := viper.New()
+	fv.SetConfigType(format)
+	if err := fv.ReadConfig(bytes.NewReader(b)); err != nil {
+		return nil, newConfigSyntaxError(err, b)
+	}
+	return fv.AllSettings(), nil
+}
+
+// configSyntaxError is the error of a config file with the position reported by the decoder.
+// The column is 0 when the decoder reports only the line.
+type configSyntaxError struct {
+	path   string // Set by readConfigFile
+	line   int
+	column int
+	msg    string
+	err    error
+}
+
+func (e *configSyntaxError) Error() string {
+	if e.column == 0 {
+		return fmt.Sprintf("line %d: %s", e.line, e.msg)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.line, e.column, e.msg)
+}
+
+func (e *configSyntaxError) Unwrap() error {
+	return e.err
+}
+
+// yamlErrorLine matches the line reported in the errors of yaml, e.g. "yaml: line 3: mapping values are not allowed".
+var yamlErrorLine = regexp.MustCompile(`^yaml: (?:unmarshal errors:\n\s*)?line (\d+): `)
+
+// newConfigSyntaxError returns the error of decoding the content with the position in it, if the decoder reports one.
+func newConfigSyntaxError(err error, b []byte) error {
+	var se *configSyntaxError
+	if errors.As(err, &se) {
+		return err
+	}
+	cause := err
+	var pe viper.ConfigParseError
+	if errors.As(err, &pe) {
+		cause = pe.Unwrap()
+	}
+	se = &configSyntaxError{msg: cause.Error(), err: err}
+	var jsonErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var posErr interface{ Position() (row, column int) } // toml
+	switch {
+	case errors.As(cause, &jsonErr):
+		se.line, se.column = offsetPosition(b, jsonErr.Offset)
+	case errors.As(cause, &typeErr):
+		se.line, se.column = offsetPosition(b, typeErr.Offset)
+	case errors.As(cause, &posErr):
+		se.line, se.column = posErr.Position()
+	default:
+		m := yamlErrorLine.FindStringSubmatch(cause.Error())
+		if m == nil {
+			return err
+		}
+		se.line, _ = strconv.Atoi(m[1])
+		se.msg = "yaml: " + strings.TrimPrefix(cause.Error(), m[0])
+	}
+	return se
+}
+
+// offsetPosition returns the line and the column of the byte just before the offset, which is where json stops.
+func offsetPosition(b []byte, offset int64) (int, int) {
+	head := b[:min(max(offset, 1), int64(len(b)))]
+	return bytes.Count(head, []byte{'\n'}) + 1, len(head) - bytes.LastIndexByte(head, '\n') - 1
+}
+
+// lowerConfigKeys lowercases the keys of the nested maps, since viper keys are case-insensitive.
+func lowerConfigKeys(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, val := range m {
+		if sub, ok := val.(map[string]any); ok {
+			val = lowerConfigKeys(sub)
+		}
+		out[strings.ToLower(k)] = val
+	}
+	return out
+}
+
+// viperCodec uses the codecs built into viper, which are not exported.
+type viperCodec struct {
+	format string
+}
+
+func (c viperCodec) Encode(m map[string]any) ([]byte, error) {
+	fs := afero.NewMemMapFs()
+	fv := viper.New()
+	fv.SetFs(fs)
+	if err := fv.MergeConfigMap(m); err != nil {
+		return nil, err
+	}
+	path := "/config." + c.format
+	if err := fv.WriteConfigAs(path); err != nil {
+		return nil, err
+	}
+	b, err := afero.ReadFile(fs, path)
+	if err != nil || bytes.HasSuffix(b, []byte{'\n'}) {
+		return b, err
+	}
+	return append(b, '\n'), nil
+}
+
+func (c viperCodec) Decode(b []byte, m map[string]any) error {
+	fv := viper.New()
+	fv.SetConfigType(c.format)
+	if err := fv.ReadConfig(bytes.NewReader(b)); err != nil {
+		return err
+	}
+	for k, val := range fv.AllSettings() {
+		m[k] = val
+	}
+	return nil
+}
+
+// jsoncCodec reads JSON with comments and trailing commas. Single-quoted strings and unquoted keys
+// of JSON5 are also accepted, while the other JSON5 extensions (e.g. hexadecimal numbers) are not.
+// It writes plain JSON, which is valid for both.
+type jsoncCodec struct{}
+
+func (jsoncCodec) Encode(m map[string]any) ([]byte, error) {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func (jsoncCodec) Decode(b []byte, m map[string]any) error {
+	// The positions are computed in the normalized content, which has the same lines.
+	normalized := normalizeJSON(b)
+	if err := json.Unmarshal(normalized, &m); err != nil {
+		return newConfigSyntaxError(err, normalized)
+	}
+	return nil
+}
+
+// normalizeJSON rewrites JSON with comments, trailing commas, single-quoted strings and unquoted keys
+// into plain JSON. Newlines are kept so that the positions in syntax errors stay meaningful.
+func normalizeJSON(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '"' || c == '\'':
+			out = append(out, '"')
+			j := i + 1
+			for ; j < len(b) && b[j] != c; j++ {
+				switch {
+				case b[j] == '\\' && j+1 < len(b):
+					if b[j+1] == '\'' {
+						out = append(out, '\'')
+					} else {
+						out = append(out, b[j], b[j+1])
+					}
+					j++
+				case b[j] == '"':
+					out = append(out, '\\', '"')
+				default:
+					out = append(out, b[j])
+				}
+			}
+			out = append(out, '"')
+			i = j
+		case c == '/' && i+1 < len(b) && (b[i+1] == '/' || b[i+1] == '*'):
+			end := skipJSONComment(b, i)
+			out = append(out, ' ')
+			out = append(out, bytes.Repeat([]byte{'\n'}, bytes.Count(b[i:end], []byte{'\n'}))...)
+			i = end - 1
+		case c == ',':
+			if next := nextJSONToken(b, i+1); next != '}' && next != ']' {
+				out = append(out, c)
+			}
+		case isJSONIdentStart(c):
+			j := i + 1
+			for j < len(b) && (isJSONIdentStart(b[j]) || b[j] >= '0' && b[j] <= '9') {
+				j++
+			}
+			if nextJSONToken(b, j) == ':' {
+				out = append(out, '"')
+				out = append(out, b[i:j]...)
+				out = append(out, '"')
+			} else {
+				out = append(out, b[i:j]...)
+			}
+			i = j - 1
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// skipJSONComment returns the index just after the comment starting at i.
+func skipJSONComment(b []byte, i int) int {
+	if b[i+1] == '/' {
+		if end := bytes.IndexByte(b[i:], '\n'); end >= 0 {
+			return i + end
+		}
+		return len(b)
+	}
+	if end := bytes.Index(b[i+2:], []byte("*/")); end >= 0 {
+		return i + 2 + end + 2
+	}
+	return len(b)
+}
+
+// nextJSONToken returns the first byte from i that is neither a space nor in a comment, or 0 at the end.
+func nextJSONToken(b []byte, i int) byte {
+	for i < len(b) {
+		switch {
+		case b[i] == ' ' || b[i] == '\t' || b[i] == '\n' || b[i] == '\r':
+			i++
+		case b[i] == '/' && i+1 < len(b) && (b[i+1] == '/' || b[i+1] == '*'):
+			i = skipJSONComment(b, i)
+		default:
+			return b[i]
+		}
+	}
+	return 0
+}
+
+func isJSONIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$'
+}
@@ -0,0 +1,78 @@
+package cobrax
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", `{"a": 1, "b": [1, 2]}`, `{"a": 1, "b": [1, 2]}`},
+		{"line comment", "{\"a\": 1 // one\n}", "{\"a\": 1  \n}"},
+		{"block comment", "{/* a\nb */\"a\": 1}", "{ \n\"a\": 1}"},
+		{"comment in string", `{"url": "http://example.com/*x*/"}`, `{"url": "http://example.com/*x*/"}`},
+		{"trailing commas", `{"a": [1, 2,], "b": 1,}`, `{"a": [1, 2], "b": 1}`},
+		{"trailing comma before comment", "{\"a\": 1, // one\n}", "{\"a\": 1  \n}"},
+		{"comma in string", `{"a": "x,}"}`, `{"a": "x,}"}`},
+		{"single quotes", `{'a': 'it\'s "b"'}`, `{"a": "it's \"b\""}`},
+		{"unquoted keys", `{a: 1, $b_2: true, c: null}`, `{"a": 1, "$b_2": true, "c": null}`},
+		{"escapes", `{"a": "\"\\\n"}`, `{"a": "\"\\\n"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(normalizeJSON([]byte(tt.in))); got != tt.want {
+				t.Errorf("normalizeJSON(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONCCodecDecode(t *testing.T) {
+	in := `{
+  // server settings
+  server: {
+    host: 'localhost',
+    port: 8080, /* default */
+  },
+  tags: ["a", "b",],
+}
+`
+	m := make(map[string]any)
+	if err := (jsoncCodec{}).Decode([]byte(in), m); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"server": map[string]any{"host": "localhost", "port": float64(8080)},
+		"tags":   []any{"a", "b"},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Decode = %v, want %v", m, want)
+	}
+}
+
+func TestJSONCCodecSyntaxError(t *testing.T) {
+	in := "{\n  // comment\n  a: 1,\n  b: [1,,],\n}\n"
+	err := (jsoncCodec{}).Decode([]byte(in), make(map[string]any))
+	var se *configSyntaxError
+	if !errors.As(err, &se) {
+		t.Fatalf("err = %v, want a configSyntaxError", err)
+	}
+	if se.line != 4 {
+		t.Errorf("line = %d, want 4", se.line)
+	}
+}
+
+func TestBindJSONCConfig(t *testing.T) {
+	v, err := bindTestConfig(t, map[string]string{"/home/u/.app.jsonc": "{\n  // c\n  name: 'app',\n}\n"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetString("name"); got != "app" {
+		t.Errorf("name = %q, want %q", got, "app")
+	}
+}
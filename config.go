@@ -1,117 +1,400 @@
 package cobrax
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
+	"time"
 
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 type ConfigOptions struct {
-	configFile      string
-	subConfigKey    string
-	configFilePaths []string // File paths without extension
-	configFileExts  []string
-	mergeConfig     bool
+	rootCmdName           string
+	configFile            string
+	subConfigKeys         []string       // Dotted keys of sub-configs from the lowest precedence
+	commandRoot           *cobra.Command // Root of the command tree whose sections are in the config
+	configFilePaths       []string       // File paths without extension
+	systemConfigFilePaths []string       // System-wide file paths without extension, with lower precedence than configFilePaths
+	systemPathsSet        bool           // Whether systemConfigFilePaths is set by WithSystemConfigFilePaths
+	configFileExts        []string
+	mergeConfig           bool
+	mergeStrategies       map[string]MergeStrategy // Strategies of dotted keys to merge config layers
+	fs                    afero.Fs
+	env                   Environment
+	flags                 *pflag.FlagSet
+	envPrefix             string
+	reloadDebounce        time.Duration
+	profile               string
+	profileFlagName       string
+	secretExecEnabled     bool
+	secretsDisabled       bool
+	upwardSearch          bool
+	upwardMarkers         []string
+	configFormat          string // Format of the config read from stdin
+	stdin                 io.Reader
+	stdinConfig           []byte
+	interpolationDisabled bool
+	strictRoot            *cobra.Command
+	strictWarnOnly        bool
+	keyMigrations         []keyMigration
+	versionMigrations     []ConfigMigration
+	keyFile               string // Key file to decrypt encrypted config files
+	keyFileFlagName       string
+	sensitivePatterns     []string // Patterns of sensitive dotted keys
+	redactionDisabled     bool
 }
 
+// BindConfigs reads config files and sets the values to viper, then binds flags and environment variables.
+// Files are read through the Fs of WithFs instead of the one set by viper.SetFs. This is a breaking change: callers
+// that set the Fs of viper to read config files from must pass it with WithFs.
 func BindConfigs(v *viper.Viper, rootCmdName string, opts ...ConfigOption) error {
+	_, err := BindConfigsWithSources(v, rootCmdName, opts...)
+	return err
+}
+
+// BindConfigsWithSources reads config files like BindConfigs and returns the ConfigSources
+// recording which layer set each key.
+func BindConfigsWithSources(v *viper.Viper, rootCmdName string, opts ...ConfigOption) (*ConfigSources, error) {
+	opt := newConfigOptions(rootCmdName, opts...)
+	sources := newConfigSources()
+	setConfigSources(v, sources)
+	loaded, err := loadConfigs(opt, sources)
+	if err != nil {
+		return sources, err
+	}
+	if err := bindLoadedConfig(v, opt, sources, loaded); err != nil {
+		return sources, err
+	}
+	return sources, nil
+}
+
+func newConfigOptions(rootCmdName string, opts ...ConfigOption) *ConfigOptions {
 	opt := &ConfigOptions{
-		configFilePaths: make([]string, 0, 12),
-		configFileExts:  []string{"json", "toml", "yaml", "yml"},
-		mergeConfig:     true,
+		configFileExts: append([]string{"json", "toml", "yaml", "yml"}, configCodecExts()...),
+		mergeConfig:    true,
+		fs:             afero.NewOsFs(),
+		env:            osEnvironment{},
+		reloadDebounce: 100 * time.Millisecond,
+		configFormat:   "yaml",
+		stdin:          os.Stdin,
 	}
+	opt.sensitivePatterns = slices.Clone(DefaultSensitiveKeyPatterns)
 	rootCmdName = strings.ToLower(rootCmdName)
-	xdgConfigHome := "$HOME/.config"
-	if xdg, exists := os.LookupEnv("XDG_CONFIG_HOME"); exists {
-		xdgConfigHome = xdg
-	}
-	opt.configFilePaths = append(opt.configFilePaths,
-		fmt.Sprintf("%s/%s/config", xdgConfigHome, rootCmdName),
-		fmt.Sprintf("$HOME/.%s", rootCmdName),
-		fmt.Sprintf("./.%s", rootCmdName))
+	opt.rootCmdName = rootCmdName
+	opt.systemConfigFilePaths = systemConfigFilePaths(rootCmdName, runtime.GOOS)
+	opt.configFilePaths = userConfigFilePaths(rootCmdName, runtime.GOOS)
 
 	// apply options
 	for _, fn := range opts {
 		fn(opt)
 	}
+	if opt.profile == "" && opt.profileFlagName != "" {
+		opt.profile = getenv(opt, profileEnvName(opt))
+	}
+	if opt.keyFile == "" {
+		opt.keyFile = getenv(opt, configKeyEnvName(opt))
+	}
+	if opt.keyFile == "" {
+		opt.keyFile = fmt.Sprintf("%s/%s/config.key", userConfigDir(runtime.GOOS), rootCmdName)
+	}
+	return opt
+}
+
+// loadedConfig is the merged content of config files before it is set to viper.
+type loadedConfig struct {
+	config map[string]any
+	layers []configLayer
+	files  []string // Loaded files from the lowest precedence
+	errs   []error  // Errors of discovered files that could not be read
+
+	secretKeys map[string]bool // Keys whose secret references are resolved, which are not interpolated
+	redactor   *redactor       // Sensitive keys marked while loading
+}
 
+// loadConfigs reads config files and applies the sub-config override without touching viper,
+// so that a failure leaves the current config as it is.
+func loadConfigs(opt *ConfigOptions, sources *ConfigSources) (*loadedConfig, error) {
+	loaded := &loadedConfig{config: make(map[string]any), redactor: newRedactor(opt, opt.commandRoot)}
 	if opt.configFile != "" {
-		v.SetConfigFile(opt.configFile) // Use config file from the flag.
-		if err := v.ReadInConfig(); err != nil {
-			return err
+		// Use config file from the flag.
+		if err := loaded.mergeFile(opt, sources, opt.configFile); err != nil {
+			return nil, err
 		}
-		logger.Info(fmt.Sprintf("using config file: %s", v.ConfigFileUsed()))
-		logger.Debug(DebugViper(v))
-		// Override sub-config
-		if opt.subConfigKey != "" {
-			if subConf := v.GetStringMap(opt.subConfigKey); len(subConf) > 0 {
-				if err := v.MergeConfigMap(subConf); err != nil {
-					return err
-				}
-				v.Set(opt.subConfigKey, nil)
-				logger.Info(fmt.Sprintf("override sub-config: %s", opt.subConfigKey))
-				logger.Debug(DebugViper(v))
+		logger.Info(fmt.Sprintf("using config file: %s", opt.configFile))
+	} else {
+		tryReadInConfig(loaded, opt, sources)
+	}
+
+	// Check unknown keys
+	if err := checkUnknownKeys(loaded, opt); err != nil {
+		return nil, err
+	}
+
+	// Apply profile
+	if err := applyProfile(loaded, opt, sources); err != nil {
+		return nil, err
+	}
+
+	// Override sub-config
+	applied := make([]string, 0, len(opt.subConfigKeys))
+	for i, key := range opt.subConfigKeys {
+		subConf := configMapAt(loaded.config, key)
+		for _, next := range opt.subConfigKeys[i+1:] {
+			// The section of the subcommand is applied next, instead of being merged as the values of the key.
+			if child, ok := strings.CutPrefix(next, key+"."); ok && subConf[child] != nil {
+				subConf = maps.Clone(subConf)
+				delete(subConf, child)
 			}
 		}
-		return nil
+		if len(subConf) > 0 {
+			mergeConfigMapsWith(loaded.config, subConf, "", opt.mergeStrategies)
+			sources.record(subConf, Source{Kind: SourceOverride, Name: key})
+			applied = append(applied, key)
+			logger.Info(fmt.Sprintf("override sub-config: %s", key))
+		}
+	}
+	// The applied sections are removed after all of them are applied, since a section may contain the next one.
+	for _, key := range applied {
+		deleteConfigValueAt(loaded.config, key)
+	}
+
+	// Resolve secret references
+	if !opt.secretsDisabled {
+		if err := resolveSecrets(loaded, opt); err != nil {
+			return nil, err
+		}
 	}
-	return tryReadInConfig(v, opt)
+
+	// Interpolate references to environment variables and other keys
+	if !opt.interpolationDisabled {
+		if err := interpolateConfig(loaded, opt); err != nil {
+			return nil, err
+		}
+	}
+	return loaded, nil
 }
 
-func tryReadInConfig(v *viper.Viper, opt *ConfigOptions) error {
+func tryReadInConfig(loaded *loadedConfig, opt *ConfigOptions, sources *ConfigSources) {
 	logger.Debug("attempting to read in config file")
-	found := false
-	for _, cf := range opt.configFilePaths {
-		for _, ext := range opt.configFileExts {
-			cf, err := filepath.Abs(os.ExpandEnv(fmt.Sprintf("%s.%s", cf, ext)))
-			if err != nil {
-				logger.Debug(err.Error())
-				continue
-			}
-			v.SetConfigFile(cf)
-			if err = v.MergeInConfig(); err != nil {
-				logger.Debug(err.Error())
-				continue
-			}
-			logger.Info(fmt.Sprintf("successfully loaded config file: %s", v.ConfigFileUsed()))
-			logger.Debug(DebugViper(v))
-			found = true
-
-			// Override sub-config
-			if opt.subConfigKey != "" {
-				if subConf := v.GetStringMap(opt.subConfigKey); len(subConf) > 0 {
-					if err := v.MergeConfigMap(subConf); err != nil {
-						return err
-					}
-					v.Set(opt.subConfigKey, nil)
-					logger.Debug(fmt.Sprintf("override sub-config: %s", opt.subConfigKey))
-					logger.Debug(DebugViper(v))
-				}
-			}
+	files := discoverConfigFiles(opt)
+	if !opt.mergeConfig {
+		// Only the first file found is read.
+		files = discoverFirstConfigFiles(opt)
+	}
+	for _, cf := range files {
+		if err := loaded.mergeFile(opt, sources, cf); err != nil {
+			logger.Warn(fmt.Sprintf("failed to read config file: %s: %v", cf, err))
+			loaded.errs = append(loaded.errs, fmt.Errorf("%s: %w", cf, err))
+			continue
+		}
+		logger.Info(fmt.Sprintf("successfully loaded config file: %s", cf))
 
-			if !opt.mergeConfig {
-				return nil
-			}
+		if !opt.mergeConfig {
+			return
 		}
 	}
-	if !found {
+	if len(loaded.files) == 0 {
 		logger.Debug("no config file found")
 	}
+}
+
+// mergeFile reads the config file and the files it includes through the Fs of opt and merges them.
+// Nothing is merged when any of them fails to be read.
+func (l *loadedConfig) mergeFile(opt *ConfigOptions, sources *ConfigSources, path string) error {
+	layers, err := readConfigLayers(opt, path, nil)
+	if err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		mergeConfigMapsWith(l.config, layer.config, "", opt.mergeStrategies)
+		l.layers = append(l.layers, layer)
+		l.files = append(l.files, layer.path)
+		sources.record(layer.config, Source{Kind: SourceFile, Name: layer.path})
+		if isEncryptedConfigFile(layer.path) {
+			markEncryptedKeysSensitive(l.redactor, layer.config)
+		}
+	}
+	return nil
+}
+
+// StdinConfigFile is the config file name to read the config from stdin.
+const StdinConfigFile = "-"
+
+// readConfigFile reads the config file through the Fs of opt, or stdin if the path is StdinConfigFile.
+func readConfigFile(opt *ConfigOptions, path string) (map[string]any, error) {
+	if path == StdinConfigFile {
+		return readStdinConfig(opt)
+	}
+	b, err := afero.ReadFile(opt.fs, path)
+	if err != nil {
+		return nil, err
+	}
+	if !isEncryptedConfigFile(path) {
+		return decodeConfigFile(path, b)
+	}
+
+	// Decrypt in memory, the content is never written in clear text.
+	key, err := readConfigKey(opt)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = decryptConfig(key, b); err != nil {
+		return nil, err
+	}
+	return decodeConfigFile(path, b)
+}
+
+// decodeConfigFile decodes the content of the config file in the format of its extension.
+func decodeConfigFile(path string, b []byte) (map[string]any, error) {
+	m, err := decodeConfig(strings.TrimPrefix(filepath.Ext(path), "."), b)
+	var se *configSyntaxError
+	if errors.As(err, &se) {
+		se.path = path
+	}
+	return m, err
+}
+
+// readStdinConfig reads the config in the format of opt from stdin.
+// The content is kept in opt so that reloading does not read stdin again.
+func readStdinConfig(opt *ConfigOptions) (map[string]any, error) {
+	if opt.stdinConfig == nil {
+		r, err := OpenOrStdIn("", opt.fs, WithStdin(opt.stdin))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		if opt.stdinConfig, err = io.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+	return decodeConfig(opt.configFormat, opt.stdinConfig)
+}
+
+// bindLoadedConfig sets the loaded config to viper and binds flags and environment variables.
+func bindLoadedConfig(v *viper.Viper, opt *ConfigOptions, sources *ConfigSources, loaded *loadedConfig) error {
+	if err := v.MergeConfigMap(loaded.config); err != nil {
+		return err
+	}
+	if len(loaded.files) > 0 {
+		v.SetConfigFile(loaded.files[len(loaded.files)-1])
+	}
+
+	sources.setRedactor(loaded.redactor)
+
+	// Bind flags
+	if opt.flags != nil {
+		if err := v.BindPFlags(opt.flags); err != nil {
+			return err
+		}
+		sources.setFlags(opt.flags)
+		opt.flags.VisitAll(loaded.redactor.markFlag)
+		if err := mergeFlagValues(v, opt.flags, loaded.config, loaded.redactor); err != nil {
+			return err
+		}
+	}
+
+	// Bind environment variables
+	if opt.envPrefix != "" {
+		bindEnvs(v, opt, sources)
+	}
+	logger.Debug(DebugViper(v))
 	return nil
 }
 
+// configMapAt returns the nested map at the dotted key, or nil if there is none.
+func configMapAt(m map[string]any, key string) map[string]any {
+	for _, k := range strings.Split(key, ".") {
+		child, ok := m[k].(map[string]any)
+		if !ok {
+			return nil
+		}
+		m = child
+	}
+	return m
+}
+
+// commandConfigKeys returns the dotted keys of the command and its ancestors, excluding the root command.
+// e.g. ["db", "db.migrate"] for "app db migrate"
+func commandConfigKeys(cmd *cobra.Command) []string {
+	names := make([]string, 0)
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		names = append([]string{strings.ToLower(c.Name())}, names...)
+	}
+	keys := make([]string, 0, len(names))
+	for i := range names {
+		keys = append(keys, strings.Join(names[:i+1], "."))
+	}
+	return keys
+}
+
+// commandSectionKeys returns the dotted keys of the sections of all the commands under root.
+func commandSectionKeys(root *cobra.Command) map[string]bool {
+	keys := make(map[string]bool)
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, c := range cmd.Commands() {
+			for _, key := range commandConfigKeys(c) {
+				keys[key] = true
+			}
+			walk(c)
+		}
+	}
+	walk(root)
+	return keys
+}
+
+// inactiveConfigSections returns the dotted keys of the sections whose values are not used as they are:
+// the profiles, and the sections of the commands of the tree given by WithOverrideByCommand.
+// The selected profile and the sections of the running command are merged into the top level instead,
+// so secrets and references are resolved only there.
+func inactiveConfigSections(opt *ConfigOptions) map[string]bool {
+	sections := map[string]bool{ProfilesKey: true}
+	if opt.commandRoot != nil {
+		maps.Copy(sections, commandSectionKeys(opt.commandRoot))
+	}
+	return sections
+}
+
+// mergeConfigMaps merges src into dst recursively. Values other than maps in src overwrite those in dst.
+func mergeConfigMaps(dst, src map[string]any) {
+	for k, sv := range src {
+		sm, ok := sv.(map[string]any)
+		if !ok {
+			dst[k] = sv
+			continue
+		}
+		dm, ok := dst[k].(map[string]any)
+		if !ok {
+			dm = make(map[string]any, len(sm))
+			dst[k] = dm
+		}
+		mergeConfigMaps(dm, sm)
+	}
+}
+
 func DebugViper(v *viper.Viper) string {
 	keys := v.AllKeys()
 	slices.SortFunc(keys, sortConfigKey)
 	buf := make([]byte, 0, 1024)
 	buf = append(buf, "Config values:\n"...)
+	sources := Sources(v)
+	r := sources.redactor()
 	for _, k := range keys {
-		buf = append(buf, fmt.Sprintf("\t%s: %v\n", k, v.Get(k))...)
+		if sources != nil {
+			buf = append(buf, fmt.Sprintf("\t%s: %v (from %s)\n", k, r.redact(k, v.Get(k)), sources.Lookup(k))...)
+		} else {
+			buf = append(buf, fmt.Sprintf("\t%s: %v\n", k, r.redact(k, v.Get(k)))...)
+		}
 	}
 	return string(buf)
 }
@@ -148,31 +431,284 @@ func WithConfigFileName(file string) ConfigOption {
 func WithConfigFileFlag(cmd *cobra.Command, flagName string) ConfigOption {
 	return func(opt *ConfigOptions) {
 		opt.configFile = cmd.Flag(flagName).Value.String()
+		opt.stdin = cmd.InOrStdin()
 	}
 }
 
+// WithConfigFormat sets the format of the config read from stdin with the config file "-". The default is yaml.
+func WithConfigFormat(format string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		if format != "" {
+			opt.configFormat = strings.ToLower(format)
+		}
+	}
+}
+
+// WithConfigFormatFlag sets the format of the config read from stdin by the flag.
+func WithConfigFormatFlag(cmd *cobra.Command, flagName string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		if f := cmd.Flag(flagName); f != nil {
+			WithConfigFormat(f.Value.String())(opt)
+		}
+	}
+}
+
+// WithConfigStdin sets the reader of the config file "-". The default is os.Stdin.
+func WithConfigStdin(stdin io.Reader) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.stdin = stdin
+	}
+}
+
+// WithProfile selects the profile in the profiles section of config files.
+func WithProfile(profile string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.profile = profile
+	}
+}
+
+// WithProfileFlag selects the profile by the flag. When the flag is not set, the environment variable
+// named PREFIX_FLAG_NAME (PREFIX is the env prefix or the root command name) is used.
+func WithProfileFlag(cmd *cobra.Command, flagName string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.profileFlagName = flagName
+		if f := cmd.Flag(flagName); f != nil {
+			opt.profile = f.Value.String()
+		}
+	}
+}
+
+// WithConfigKeyFile sets the key file to decrypt encrypted config files such as config.enc.yaml.
+// The default is $PREFIX_CONFIG_KEY (PREFIX is the env prefix or the root command name),
+// or $XDG_CONFIG_HOME/<app>/config.key.
+func WithConfigKeyFile(path string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.keyFile = path
+	}
+}
+
+// WithConfigKeyFileFlag sets the key file by the flag. When the flag is not set, the environment variable
+// named PREFIX_FLAG_NAME (PREFIX is the env prefix or the root command name) is used.
+func WithConfigKeyFileFlag(cmd *cobra.Command, flagName string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.keyFileFlagName = flagName
+		if f := cmd.Flag(flagName); f != nil {
+			opt.keyFile = f.Value.String()
+		}
+	}
+}
+
+// WithEnvironment sets the environment variables and the working directory used to discover config files,
+// expand paths and select the profile. The default is the process environment.
+// Environment variables bound to keys are still read from the process by viper.
+func WithEnvironment(env Environment) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.env = env
+	}
+}
+
+// WithConfigFilePaths sets the config file paths without extension from the lowest precedence.
+// They replace all the default paths including the system-wide ones, which are searched before them only if
+// WithSystemConfigFilePaths is also given.
 func WithConfigFilePaths(paths ...string) ConfigOption {
 	return func(opt *ConfigOptions) {
 		opt.configFilePaths = paths
+		if !opt.systemPathsSet {
+			opt.systemConfigFilePaths = nil
+		}
+	}
+}
+
+// WithSystemConfigFilePaths sets the system-wide config file paths without extension from the lowest precedence.
+// The default is /etc/<app>/config and <app>/config in each of $XDG_CONFIG_DIRS (%ProgramData% on Windows).
+// Passing no path disables the system-wide config files.
+func WithSystemConfigFilePaths(paths ...string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.systemConfigFilePaths = paths
+		opt.systemPathsSet = true
 	}
 }
 
 func WithOverrideBy(key string) ConfigOption {
 	return func(opt *ConfigOptions) {
-		opt.subConfigKey = strings.ToLower(key)
+		opt.subConfigKeys = []string{strings.ToLower(key)}
+	}
+}
+
+// WithOverrideByCommand overrides config values by the sections along the command path.
+// For "app db migrate", the "db" section and then the "db.migrate" section are applied,
+// mirroring the tree of GetFlags.
+func WithOverrideByCommand(cmd *cobra.Command) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.subConfigKeys = commandConfigKeys(cmd)
+		opt.commandRoot = cmd.Root()
 	}
 }
 
 func WithOverrideDisabled() ConfigOption {
 	return func(opt *ConfigOptions) {
-		opt.subConfigKey = ""
+		opt.subConfigKeys = nil
 	}
 }
 
+// WithMergeConfig sets whether all the discovered config files are merged, which is the default.
+// When it is false, only the first file found is read, searching the user's paths in the configured order
+// (the XDG config dir first by default) and then the system-wide paths.
 func WithMergeConfig(merge bool) ConfigOption {
 	return func(opt *ConfigOptions) {
 		opt.mergeConfig = merge
 	}
 }
 
+// WithMergeStrategy sets how the value of the dotted key in a config layer is merged into the lower layers:
+// config files loaded earlier, the top-level values for a profile and for a sub-config section.
+func WithMergeStrategy(key string, strategy MergeStrategy) ConfigOption {
+	return func(opt *ConfigOptions) {
+		if opt.mergeStrategies == nil {
+			opt.mergeStrategies = make(map[string]MergeStrategy)
+		}
+		opt.mergeStrategies[strings.ToLower(key)] = strategy
+	}
+}
+
+// WithFs sets the filesystem config files, included files and secret files are read from. The default is the OS
+// filesystem: the Fs set by viper.SetFs is not used, so pass the same Fs to both.
+func WithFs(fs afero.Fs) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.fs = fs
+	}
+}
+
+// WithEnvPrefix binds environment variables named PREFIX_KEY to every config key and flag.
+// Dots and hyphens in keys are replaced with underscores (e.g. "server.listen-addr" is set by PREFIX_SERVER_LISTEN_ADDR).
+// When the sub-config override is enabled, PREFIX_SUB_KEY takes precedence over PREFIX_KEY,
+// and PREFIX_DB_MIGRATE_KEY over PREFIX_DB_KEY for the command path.
+func WithEnvPrefix(prefix string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.envPrefix = prefix
+	}
+}
+
+// WithReloadDebounce sets how long WatchConfigs waits for successive changes of config files before reloading them.
+func WithReloadDebounce(d time.Duration) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.reloadDebounce = d
+	}
+}
+
+// WithUpwardSearch searches the project-level config file "./.<app>" in the current directory and its parents
+// up to the filesystem root or the nearest directory containing any of the markers (".git" and "go.mod" by default).
+// All found files are merged, and the nearest one has the highest precedence.
+func WithUpwardSearch(markers ...string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.upwardSearch = true
+		opt.upwardMarkers = markers
+		if len(markers) == 0 {
+			opt.upwardMarkers = []string{".git", "go.mod"}
+		}
+	}
+}
+
+// WithInterpolationDisabled disables expanding ${ENV_VAR}, ${other.key} and ${.key} in config values.
+func WithInterpolationDisabled() ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.interpolationDisabled = true
+	}
+}
+
+// WithStrict rejects config keys that do not match any flag of the command tree of root,
+// either at the top level or in the section of the command defining it.
+func WithStrict(root *cobra.Command) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.strictRoot = root
+		opt.strictWarnOnly = false
+	}
+}
+
+// WithStrictWarn logs config keys unknown to the command tree of root as warnings instead of rejecting them.
+func WithStrictWarn(root *cobra.Command) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.strictRoot = root
+		opt.strictWarnOnly = true
+	}
+}
+
+// WithRenamedKey moves the value of the old key in config files to the new key, warning that the old key is deprecated.
+func WithRenamedKey(oldKey, newKey string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.keyMigrations = append(opt.keyMigrations, keyMigration{oldKey: strings.ToLower(oldKey), newKey: strings.ToLower(newKey)})
+	}
+}
+
+// WithRemovedKey ignores the key in config files, warning with the message.
+func WithRemovedKey(key, message string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.keyMigrations = append(opt.keyMigrations, keyMigration{oldKey: strings.ToLower(key), message: message})
+	}
+}
+
+// WithConfigMigrations enables versioning of config files by the config_version key.
+// migrations[i] migrates a config of version i to version i+1, so the current version is len(migrations).
+// Files without config_version are version 0, and files newer than the current version are refused.
+// The files written by the config commands and PrintConfigCmd are stamped with the current version.
+func WithConfigMigrations(migrations ...ConfigMigration) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.versionMigrations = migrations
+	}
+}
+
+// WithSecretExec enables exec:// secret references, which run commands written in config files.
+// Enable it only when every config file that can be discovered, including ./.app and the files found by
+// WithUpwardSearch, is trusted.
+func WithSecretExec() ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.secretExecEnabled = true
+	}
+}
+
+// WithSecretReferencesDisabled disables resolving file://, env:// and exec:// secret references,
+// so that such values are used as they are.
+func WithSecretReferencesDisabled() ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.secretsDisabled = true
+	}
+}
+
+// WithSensitiveKeys marks the config keys matching the patterns as sensitive in addition to
+// DefaultSensitiveKeyPatterns, so that their values are printed as "****" by DebugViper, the config commands and
+// error messages. A pattern is matched against the whole dotted key in lower case with path.Match,
+// e.g. "*token*" or "db.password".
+func WithSensitiveKeys(patterns ...string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		for _, p := range patterns {
+			opt.sensitivePatterns = append(opt.sensitivePatterns, strings.ToLower(p))
+		}
+	}
+}
+
+// WithSensitiveKeyPatterns replaces the patterns of sensitive config keys, including DefaultSensitiveKeyPatterns.
+func WithSensitiveKeyPatterns(patterns ...string) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.sensitivePatterns = make([]string, 0, len(patterns))
+		for _, p := range patterns {
+			opt.sensitivePatterns = append(opt.sensitivePatterns, strings.ToLower(p))
+		}
+	}
+}
+
+// WithRedactionDisabled prints sensitive values in clear text. Use it only for local debugging,
+// e.g. behind a hidden flag.
+func WithRedactionDisabled() ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.redactionDisabled = true
+	}
+}
+
+// WithFlags binds the flags to viper after reading config files, so that they are recorded as the highest layer.
+func WithFlags(flags *pflag.FlagSet) ConfigOption {
+	return func(opt *ConfigOptions) {
+		opt.flags = flags
+	}
+}
+
 //</editor-fold>
@@ -0,0 +1,711 @@
+package cobrax
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// ConfigCmd returns the command group manipulating the user's config file.
+// The options should be the same as the ones used to bind configs, so that the same file is found.
+func ConfigCmd(name string, v *viper.Viper, fs afero.Fs, opts ...ConfigOption) *cobra.Command {
+	configCmd := &cobra.Command{}
+	configCmd.Use = name
+	configCmd.Short = "Manage configuration file"
+	configCmd.Args = cobra.NoArgs
+
+	configCmd.AddCommand(configGetCmd(v))
+	configCmd.AddCommand(configSetCmd(fs, opts))
+	configCmd.AddCommand(configUnsetCmd(fs, opts))
+	configCmd.AddCommand(configListCmd(fs, opts))
+	configCmd.AddCommand(configPathCmd(fs, opts))
+	configCmd.AddCommand(configPathsCmd(fs, opts))
+	configCmd.AddCommand(configEditCmd(fs, opts))
+	configCmd.AddCommand(configMigrateCmd(fs, opts))
+	configCmd.AddCommand(configDiffCmd(v))
+	configCmd.AddCommand(configValidateCmd(fs, opts))
+	configCmd.AddCommand(configEncryptCmd(fs, opts))
+	configCmd.AddCommand(configDecryptCmd(fs, opts))
+	return configCmd
+}
+
+func configGetCmd(v *viper.Viper) *cobra.Command {
+	getCmd := &cobra.Command{}
+	getCmd.Use = "get <key>"
+	getCmd.Short = "Print the effective value of the configuration key"
+	getCmd.Args = cobra.ExactArgs(1)
+	getCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if !v.IsSet(args[0]) {
+			return fmt.Errorf("key %q is not set", args[0])
+		}
+		key := strings.ToLower(args[0])
+		r := Sources(v).redactor()
+		val := r.redact(key, v.Get(key))
+		if m, ok := val.(map[string]any); ok {
+			val = r.redactMap(key, m)
+		}
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), val)
+		return err
+	}
+	return getCmd
+}
+
+func configSetCmd(fs afero.Fs, opts []ConfigOption) *cobra.Command {
+	setCmd := &cobra.Command{}
+	setCmd.Use = "set <key> <value>"
+	setCmd.Short = "Set the value of the configuration key in the configuration file"
+	setCmd.Args = cobra.ExactArgs(2)
+	setCmd.Annotations = map[string]string{skipConfigAnnotation: "true"}
+	setCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		key := strings.ToLower(args[0])
+		r := newRedactor(configCmdOptions(cmd, fs, opts), cmd.Root())
+		val, err := parseConfigValue(r, cmd.Root(), key, args[1])
+		if err != nil {
+			return err
+		}
+		return updateConfigFile(cmd, fs, opts, func(m map[string]any) error {
+			setConfigValueAt(m, key, val)
+			return nil
+		})
+	}
+	return setCmd
+}
+
+func configUnsetCmd(fs afero.Fs, opts []ConfigOption) *cobra.Command {
+	unsetCmd := &cobra.Command{}
+	unsetCmd.Use = "unset <key>"
+	unsetCmd.Short = "Remove the configuration key from the configuration file"
+	unsetCmd.Args = cobra.ExactArgs(1)
+	unsetCmd.Annotations = map[string]string{skipConfigAnnotation: "true"}
+	unsetCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		key := strings.ToLower(args[0])
+		return updateConfigFile(cmd, fs, opts, func(m map[string]any) error {
+			if !deleteConfigValueAt(m, key) {
+				return fmt.Errorf("key %q is not set in the configuration file", key)
+			}
+			return nil
+		})
+	}
+	return unsetCmd
+}
+
+func configListCmd(fs afero.Fs, opts []ConfigOption) *cobra.Command {
+	listCmd := &cobra.Command{}
+	listCmd.Use = "list"
+	listCmd.Short = "List the keys and values in the configuration file"
+	listCmd.Args = cobra.NoArgs
+	listCmd.Annotations = map[string]string{skipConfigAnnotation: "true"}
+	listCmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		opt, path, err := writableConfigFile(cmd, fs, opts)
+		if err != nil {
+			return err
+		}
+		m, err := readConfigFileIfExists(opt, path)
+		if err != nil {
+			return err
+		}
+		r := newRedactor(opt, cmd.Root())
+		if isEncryptedConfigFile(path) {
+			markEncryptedKeysSensitive(r, m)
+		}
+		keys := make([]string, 0)
+		vals := make(map[string]any)
+		flattenConfigMap("", m, func(key string, val any) {
+			keys = append(keys, key)
+			vals[key] = val
+		})
+		slices.SortFunc(keys, sortConfigKey)
+		for _, k := range keys {
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s=%v\n", k, r.redact(k, vals[k])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return listCmd
+}
+
+func configPathCmd(fs afero.Fs, opts []ConfigOption) *cobra.Command {
+	pathCmd := &cobra.Command{}
+	pathCmd.Use = "path"
+	pathCmd.Short = "Print the path of the configuration file to be written"
+	pathCmd.Args = cobra.NoArgs
+	pathCmd.Annotations = map[string]string{skipConfigAnnotation: "true"}
+	pathCmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		_, path, err := writableConfigFile(cmd, fs, opts)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), path)
+		return err
+	}
+	return pathCmd
+}
+
+func configEditCmd(fs afero.Fs, opts []ConfigOption) *cobra.Command {
+	editCmd := &cobra.Command{}
+	editCmd.Use = "edit"
+	editCmd.Short = "Open the configuration file with $EDITOR"
+	editCmd.Args = cobra.NoArgs
+	editCmd.Annotations = map[string]string{skipConfigAnnotation: "true"}
+	editCmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		opt, path, err := writableConfigFile(cmd, fs, opts)
+		if err != nil {
+			return err
+		}
+		return editConfigFile(cmd, opt, path)
+	}
+	return editCmd
+}
+
+func configMigrateCmd(fs afero.Fs, opts []ConfigOption) *cobra.Command {
+	migrateCmd := &cobra.Command{}
+	migrateCmd.Use = "migrate"
+	migrateCmd.Short = "Rewrite deprecated keys and old versions of the configuration file"
+	migrateCmd.Args = cobra.NoArgs
+	migrateCmd.Annotations = map[string]string{skipConfigAnnotation: "true"}
+	migrateCmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		opt, path, err := writableConfigFile(cmd, fs, opts)
+		if err != nil {
+			return err
+		}
+		m, err := readConfigFile(opt, path)
+		if err != nil {
+			return err
+		}
+		m, migrated, err := migrateConfigVersion(opt, path, m)
+		if err != nil {
+			return err
+		}
+		stampConfigVersion(opt, m)
+		migrated = append(migrated, migrateKeys(opt, m)...)
+		if len(migrated) == 0 {
+			_, err := fmt.Fprintf(cmd.OutOrStdout(), "%s has no deprecated keys\n", path)
+			return err
+		}
+		if err := writeConfigFile(opt, path, m); err != nil {
+			return err
+		}
+		for _, d := range migrated {
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "migrated %s\n", d); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return migrateCmd
+}
+
+func configPathsCmd(fs afero.Fs, opts []ConfigOption) *cobra.Command {
+	pathsCmd := &cobra.Command{}
+	pathsCmd.Use = "paths"
+	pathsCmd.Short = "List the paths searched for configuration files from the highest precedence"
+	pathsCmd.Args = cobra.NoArgs
+	pathsCmd.Annotations = map[string]string{skipConfigAnnotation: "true"}
+	pathsCmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		opt := configCmdOptions(cmd, fs, opts)
+		w := cmd.OutOrStdout()
+		if f := rootConfigFlag(cmd, configFlagAnnotation); f != nil && f.Value.String() != "" {
+			_, err := fmt.Fprintf(w, "%s (given by --%s, the paths below are not searched)\n", f.Value.String(), f.Name)
+			if err != nil {
+				return err
+			}
+		}
+		candidates := configFileCandidates(opt)
+		system := len(candidates) - len(userConfigFileCandidates(opt))
+		for i := len(candidates) - 1; i >= 0; i-- {
+			scope := "user"
+			if i < system {
+				scope = "system"
+			}
+			files := existingConfigFiles(opt, candidates[i])
+			if len(files) == 0 {
+				path, err := absPath(opt, expandEnv(opt, candidates[i]))
+				if err != nil {
+					return err
+				}
+				if _, err := fmt.Fprintf(w, "%s.{%s} (%s)\n", path, strings.Join(opt.configFileExts, ","), scope); err != nil {
+					return err
+				}
+				continue
+			}
+			for j := len(files) - 1; j >= 0; j-- {
+				if _, err := fmt.Fprintf(w, "%s (%s, found)\n", files[j], scope); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return pathsCmd
+}
+
+func configEncryptCmd(fs afero.Fs, opts []ConfigOption) *cobra.Command {
+	encryptCmd := &cobra.Command{}
+	encryptCmd.Use = "encrypt [file]"
+	encryptCmd.Short = "Encrypt the configuration file into <name>.enc.<ext> with the key file"
+	encryptCmd.Long = "Encrypt the configuration file into <name>.enc.<ext> with the key file.\n" +
+		"The key file is generated when it does not exist. Keep it out of version control."
+	encryptCmd.Args = cobra.MaximumNArgs(1)
+	encryptCmd.Annotations = map[string]string{skipConfigAnnotation: "true"}
+	remove := encryptCmd.Flags().Bool("remove", false, "Remove the plain configuration file after encryption")
+	encryptCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		opt, path, err := configFileArg(cmd, fs, opts, args)
+		if err != nil {
+			return err
+		}
+		if isEncryptedConfigFile(path) {
+			return fmt.Errorf("%s is already encrypted", path)
+		}
+		content, err := afero.ReadFile(opt.fs, path)
+		if err != nil {
+			return err
+		}
+		if _, err := readConfigFile(opt, path); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+		key, err := readOrGenerateConfigKey(opt)
+		if err != nil {
+			return err
+		}
+		encrypted, err := encryptConfig(key, content)
+		if err != nil {
+			return err
+		}
+		dst := encryptedConfigPath(path)
+		if err := writeFileAtomic(opt.fs, dst, encrypted); err != nil {
+			return err
+		}
+		if *remove {
+			if err := opt.fs.Remove(path); err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "encrypted %s to %s\n", path, dst)
+		return err
+	}
+	return encryptCmd
+}
+
+func configDecryptCmd(fs afero.Fs, opts []ConfigOption) *cobra.Command {
+	decryptCmd := &cobra.Command{}
+	decryptCmd.Use = "decrypt [file]"
+	decryptCmd.Short = "Print the decrypted content of the encrypted configuration file"
+	decryptCmd.Args = cobra.MaximumNArgs(1)
+	decryptCmd.Annotations = map[string]string{skipConfigAnnotation: "true"}
+	decryptCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		opt, path, err := configFileArg(cmd, fs, opts, args)
+		if err != nil {
+			return err
+		}
+		if !isEncryptedConfigFile(path) {
+			return fmt.Errorf("%s is not an encrypted configuration file", path)
+		}
+		content, err := afero.ReadFile(opt.fs, path)
+		if err != nil {
+			return err
+		}
+		key, err := readConfigKey(opt)
+		if err != nil {
+			return err
+		}
+		plaintext, err := decryptConfig(key, content)
+		if err != nil {
+			return err
+		}
+		_, err = cmd.OutOrStdout().Write(plaintext)
+		return err
+	}
+	return decryptCmd
+}
+
+// configCmdOptions returns the options of the config commands.
+// The flags of the root command are applied here, since RootPersistentPreRunE skips the commands.
+func configCmdOptions(cmd *cobra.Command, fs afero.Fs, opts []ConfigOption) *ConfigOptions {
+	options := append(rootConfigFlagOptions(cmd), WithFs(fs))
+	opt := newConfigOptions(cmd.Root().Name(), append(options, opts...)...)
+	if opt.commandRoot == nil {
+		// The sections of the commands are migrated by the commands writing the file.
+		opt.commandRoot = cmd.Root()
+	}
+	return opt
+}
+
+// configFileArg returns the config file given as the argument, or the one to be written.
+func configFileArg(cmd *cobra.Command, fs afero.Fs, opts []ConfigOption, args []string) (*ConfigOptions, string, error) {
+	if len(args) == 0 {
+		return writableConfigFile(cmd, fs, opts)
+	}
+	opt := configCmdOptions(cmd, fs, opts)
+	path, err := absPath(opt, args[0])
+	return opt, path, err
+}
+
+// writableConfigFile returns the config file to be written: the file given by the config flag,
+// the existing user's file with the highest precedence, or the first user's candidate in yaml.
+// System-wide files are never chosen.
+func writableConfigFile(cmd *cobra.Command, fs afero.Fs, opts []ConfigOption) (*ConfigOptions, string, error) {
+	opt := configCmdOptions(cmd, fs, opts)
+	if f := rootConfigFlag(cmd, configFlagAnnotation); f != nil && f.Value.String() != "" {
+		if f.Value.String() == StdinConfigFile {
+			return nil, "", errors.New("cannot write the configuration to stdin")
+		}
+		return opt, f.Value.String(), nil
+	}
+	candidates := userConfigFileCandidates(opt)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if files := existingConfigFiles(opt, candidates[i]); len(files) > 0 {
+			return opt, files[len(files)-1], nil
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, "", errors.New("no configuration file path")
+	}
+	path, err := absPath(opt, expandEnv(opt, candidates[0]+".yaml"))
+	return opt, path, err
+}
+
+func readConfigFileIfExists(opt *ConfigOptions, path string) (map[string]any, error) {
+	if exists, err := afero.Exists(opt.fs, path); err != nil {
+		return nil, err
+	} else if !exists {
+		return make(map[string]any), nil
+	}
+	return readConfigFile(opt, path)
+}
+
+// updateConfigFile reads the config file, updates it with fn and writes it back in the same format.
+// The file is migrated to the current config_version first, since fn sets the keys of the current version.
+func updateConfigFile(cmd *cobra.Command, fs afero.Fs, opts []ConfigOption, fn func(map[string]any) error) error {
+	opt, path, err := writableConfigFile(cmd, fs, opts)
+	if err != nil {
+		return err
+	}
+	m, err := readConfigFileIfExists(opt, path)
+	if err != nil {
+		return err
+	}
+	if len(m) > 0 {
+		var migrated []string
+		if m, migrated, err = migrateConfigVersion(opt, path, m); err != nil {
+			return err
+		}
+		for _, d := range migrated {
+			logger.Info(fmt.Sprintf("migrated %s", d))
+		}
+	}
+	stampConfigVersion(opt, m)
+	if err := fn(m); err != nil {
+		return err
+	}
+	return writeConfigFile(opt, path, m)
+}
+
+// writeConfigFile writes the config in the format of the extension of the path.
+// The file is replaced atomically by renaming a temporary file in the same directory.
+// Encrypted config files are encrypted again with the key.
+func writeConfigFile(opt *ConfigOptions, path string, m map[string]any) error {
+	format, err := configFileFormat(path)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := encodeConfig(&buf, m, format); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if isEncryptedConfigFile(path) {
+		key, err := readConfigKey(opt)
+		if err != nil {
+			return err
+		}
+		if data, err = encryptConfig(key, data); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(opt.fs, path, data)
+}
+
+func writeFileAtomic(fs afero.Fs, path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := fs.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	mode := os.FileMode(0o644)
+	if info, err := fs.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := afero.TempFile(fs, dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	renamed := false
+	defer func() {
+		if !renamed {
+			_ = fs.Remove(tmp.Name())
+		}
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := fs.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	if err := fs.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	renamed = true
+	logger.Info(fmt.Sprintf("wrote config file: %s", path))
+	return nil
+}
+
+// configFileFormat returns the format to write the config file in.
+func configFileFormat(path string) (PrintConfigFormat, error) {
+	switch ext := strings.TrimPrefix(filepath.Ext(path), "."); ext {
+	case "yaml", "yml":
+		return YAML, nil
+	case "json":
+		return JSON, nil
+	case "toml":
+		return TOML, nil
+	default:
+		if _, ok := lookupConfigCodec(ext); ok {
+			return PrintConfigFormat(strings.ToLower(ext)), nil
+		}
+		return "", fmt.Errorf("unsupported configuration format to write: %q", ext)
+	}
+}
+
+// editConfigFile opens a copy of the config file with the editor, and replaces the file with it if it is valid.
+func editConfigFile(cmd *cobra.Command, opt *ConfigOptions, path string) error {
+	if _, err := configFileFormat(path); err != nil {
+		return err
+	}
+	content, err := afero.ReadFile(opt.fs, path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	base := filepath.Base(path)
+	var key []byte
+	if isEncryptedConfigFile(path) {
+		if key, err = readConfigKey(opt); err != nil {
+			return err
+		}
+		if len(content) > 0 {
+			if content, err = decryptConfig(key, content); err != nil {
+				return err
+			}
+		}
+		ext := filepath.Ext(base)
+		base = strings.TrimSuffix(base, encryptedConfigExt+ext) + ext
+	}
+	if len(content) == 0 && len(opt.versionMigrations) > 0 {
+		// A new file starts with the current config_version.
+		m := make(map[string]any)
+		stampConfigVersion(opt, m)
+		format, _ := configFileFormat(path)
+		var buf bytes.Buffer
+		if err := encodeConfig(&buf, m, format); err != nil {
+			return err
+		}
+		content = buf.Bytes()
+	}
+
+	// The editor works on the OS filesystem even when opt.fs is not.
+	tmp, err := os.CreateTemp("", "*-"+base)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	editor := strings.Fields(editorCommand())
+	c := exec.Command(editor[0], append(editor[1:], tmp.Name())...)
+	c.Stdin, c.Stdout, c.Stderr = cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr()
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("failed to run editor: %w", err)
+	}
+
+	edited, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(content, edited) {
+		logger.Info("config file is not changed")
+		return nil
+	}
+	if _, err := readConfigFile(&ConfigOptions{fs: afero.NewOsFs()}, tmp.Name()); err != nil {
+		return fmt.Errorf("invalid configuration, %s is not changed: %w", path, err)
+	}
+	if key != nil {
+		if edited, err = encryptConfig(key, edited); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(opt.fs, path, edited)
+}
+
+func editorCommand() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(env)); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// parseConfigValue parses the value in the type of the flag matching the key.
+// The value is kept as a string when there is no matching flag.
+func parseConfigValue(r *redactor, root *cobra.Command, key, s string) (any, error) {
+	f := lookupConfigFlag(root, key)
+	if f == nil {
+		return s, nil
+	}
+	val, err := parseFlagValue(f, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q for %s (%s): %w", r.redact(key, s), key, f.Value.Type(), err)
+	}
+	return val, nil
+}
+
+// parseFlagValue parses the string in the type of the flag.
+func parseFlagValue(f *pflag.Flag, s string) (any, error) {
+	var val any
+	var err error
+	switch typ := f.Value.Type(); typ {
+	case "bool":
+		val, err = strconv.ParseBool(s)
+	case "int", "int8", "int16", "int32", "int64", "count":
+		val, err = strconv.ParseInt(s, 0, 64)
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		val, err = strconv.ParseUint(s, 0, 64)
+	case "float32", "float64":
+		val, err = strconv.ParseFloat(s, 64)
+	case "duration":
+		_, err = time.ParseDuration(s)
+		val = s
+	case "stringSlice", "stringArray":
+		val = splitConfigList(s)
+	case "intSlice", "int32Slice", "int64Slice":
+		ints := make([]int64, 0)
+		for _, e := range splitConfigList(s) {
+			i, perr := strconv.ParseInt(e, 0, 64)
+			if perr != nil {
+				err = perr
+				break
+			}
+			ints = append(ints, i)
+		}
+		val = ints
+	default:
+		val = s
+	}
+	return val, err
+}
+
+func splitConfigList(s string) []string {
+	list := make([]string, 0)
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// lookupConfigFlag returns the flag configured by the dotted key, following the tree of GetFlags.
+// A key without command sections may configure a flag of any command, since it applies to all of them.
+func lookupConfigFlag(root *cobra.Command, key string) *pflag.Flag {
+	cmd := root
+	names := strings.Split(key, ".")
+	for len(names) > 1 {
+		sub := findSubCommand(cmd, names[0])
+		if sub == nil {
+			break
+		}
+		cmd, names = sub, names[1:]
+	}
+	name := strings.Join(names, ".")
+	if f := cmd.Flags().Lookup(name); f != nil {
+		return f
+	}
+	if cmd == root {
+		return lookupFlagInTree(root, name)
+	}
+	return nil
+}
+
+func findSubCommand(cmd *cobra.Command, name string) *cobra.Command {
+	for _, c := range cmd.Commands() {
+		if strings.ToLower(c.Name()) == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func lookupFlagInTree(cmd *cobra.Command, name string) *pflag.Flag {
+	if f := cmd.Flags().Lookup(name); f != nil {
+		return f
+	}
+	for _, c := range cmd.Commands() {
+		if f := lookupFlagInTree(c, name); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// setConfigValueAt sets the value at the dotted key, creating intermediate maps.
+func setConfigValueAt(m map[string]any, key string, val any) {
+	names := strings.Split(key, ".")
+	for _, name := range names[:len(names)-1] {
+		child, ok := m[name].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			m[name] = child
+		}
+		m = child
+	}
+	m[names[len(names)-1]] = val
+}
+
+// deleteConfigValueAt deletes the value at the dotted key and the maps left empty.
+func deleteConfigValueAt(m map[string]any, key string) bool {
+	name, rest, nested := strings.Cut(key, ".")
+	if !nested {
+		_, ok := m[name]
+		delete(m, name)
+		return ok
+	}
+	child, ok := m[name].(map[string]any)
+	if !ok || !deleteConfigValueAt(child, rest) {
+		return false
+	}
+	if len(child) == 0 {
+		delete(m, name)
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package cobrax
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestConfigCmdWithBrokenConfig(t *testing.T) {
+	fs := newTestFs(t, map[string]string{"/work/.app.yaml": "name: app\ntoken: file:///run/secrets/missing\n"})
+	env := newTestEnvironment(nil)
+	v := viper.New()
+	rootCmd := NewRoot(v)
+	rootCmd.Use = "app"
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return RootPersistentPreRunE(cmd, v, fs, args, WithEnvironment(env))
+	}
+	serveCmd := &cobra.Command{}
+	serveCmd.Use = "serve"
+	serveCmd.RunE = func(*cobra.Command, []string) error { return nil }
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(ConfigCmd("config", v, fs, WithEnvironment(env)))
+
+	rootCmd.SetArgs([]string{"serve"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Fatal("serve succeeded with the broken config, want an error")
+	}
+	rootCmd.SetArgs([]string{"config", "unset", "token"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := afero.ReadFile(fs, "/work/.app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(content); got != "name: app\n" {
+		t.Errorf("config file = %q, want the token removed", got)
+	}
+}
@@ -0,0 +1,123 @@
+package cobrax
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func configDiffCmd(v *viper.Viper) *cobra.Command {
+	var outFormat PrintConfigFormat
+	diffCmd := &cobra.Command{}
+	diffCmd.Use = "diff"
+	diffCmd.Short = "Print the configuration keys whose effective values differ from the defaults"
+	diffCmd.Args = cobra.NoArgs
+	diffCmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		entries := configDefaultEntries(v, cmd.Root(), cmd)
+		if outFormat == "" {
+			return writeConfigDiff(cmd.OutOrStdout(), entries, Sources(v).redactor())
+		}
+		m := make(map[string]any)
+		for _, e := range entries {
+			if e.changed {
+				setConfigValueAt(m, e.key, e.value)
+			}
+		}
+		return PrintConfig(cmd.OutOrStdout(), m, outFormat)
+	}
+	diffCmd.Flags().Var(&outFormat, "format", "The output format {"+strings.Join(printConfigFormats(), "|")+"} instead of the unified diff")
+	return diffCmd
+}
+
+// configDefaultEntry is the default and the effective value of the key configuring a flag.
+type configDefaultEntry struct {
+	key     string
+	def     string
+	value   any
+	current string // value in the form of the default
+	changed bool
+}
+
+// configDefaultEntries returns the entries of the flags in the tree of GetFlags, sorted by the key.
+// The flags of the command itself (e.g. --format of "config diff") are skipped.
+func configDefaultEntries(v *viper.Viper, root, self *cobra.Command) []configDefaultEntry {
+	selfKey := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(self.CommandPath(), root.Name()+" "), " ", ".")) + "."
+	entries := make([]configDefaultEntry, 0)
+	visitConfigFlags(root, nil, func(path []string, f *pflag.Flag) {
+		key := strings.ToLower(strings.Join(append(slices.Clip(path), f.Name), "."))
+		if strings.HasPrefix(key, selfKey) {
+			return
+		}
+		e := configDefaultEntry{key: key, def: f.DefValue}
+		if val, ok := effectiveConfigValue(v, key, f.Name); ok {
+			e.value, e.current = val, canonicalFlagValue(f, val)
+			e.changed = e.current != canonicalFlagValue(f, f.DefValue)
+		}
+		entries = append(entries, e)
+	})
+	slices.SortFunc(entries, func(a, b configDefaultEntry) int { return sortConfigKey(a.key, b.key) })
+	return entries
+}
+
+// effectiveConfigValue returns the value of the key with the command sections,
+// or the top-level value of the flag name, which applies to all commands.
+func effectiveConfigValue(v *viper.Viper, key, name string) (any, bool) {
+	if v.IsSet(key) {
+		return v.Get(key), true
+	}
+	if key != name && v.IsSet(name) {
+		return v.Get(name), true
+	}
+	return nil, false
+}
+
+// canonicalFlagValue returns the string of the value in the type of the flag,
+// so that e.g. 8080 in a config file equals the default "8080" and "1m" equals "1m0s".
+func canonicalFlagValue(f *pflag.Flag, val any) string {
+	typ := f.Value.Type()
+	if s, ok := val.(string); ok {
+		if strings.HasSuffix(typ, "Slice") || strings.HasSuffix(typ, "Array") {
+			s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+		}
+		if parsed, err := parseFlagValue(f, s); err == nil {
+			val = parsed
+		}
+	}
+	if typ == "duration" {
+		if d, err := time.ParseDuration(fmt.Sprint(val)); err == nil {
+			return d.String()
+		}
+	}
+	if list, ok := toAnySlice(val); ok {
+		elems := make([]string, len(list))
+		for i, e := range list {
+			elems[i] = fmt.Sprint(e)
+		}
+		return "[" + strings.Join(elems, ",") + "]"
+	}
+	return fmt.Sprint(val)
+}
+
+// writeConfigDiff writes the changed entries as a unified diff without context,
+// from the listing of the defaults to the listing of the effective values.
+func writeConfigDiff(w io.Writer, entries []configDefaultEntry, r *redactor) error {
+	if _, err := fmt.Fprint(w, "--- defaults\n+++ effective\n"); err != nil {
+		return err
+	}
+	for i, e := range entries {
+		if !e.changed {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "@@ -%d +%d @@\n-%s: %v\n+%s: %v\n", i+1, i+1, e.key, r.redact(e.key, e.def), e.key, r.redact(e.key, e.current))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
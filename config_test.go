@@ -0,0 +1,35 @@
+package cobrax
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestOverrideBySubConfig(t *testing.T) {
+	root := &cobra.Command{Use: "app"}
+	serve := &cobra.Command{Use: "serve"}
+	db := &cobra.Command{Use: "db"}
+	root.AddCommand(serve)
+	serve.AddCommand(db)
+	content := "name: app\nport: 80\nserve:\n  port: 8080\n  db:\n    host: db\n"
+	v, err := bindLocalConfig(t, content, nil, WithOverrideByCommand(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetInt("port"); got != 8080 {
+		t.Errorf("port = %d, want the value of the serve section", got)
+	}
+	if got := v.GetString("host"); got != "db" {
+		t.Errorf("host = %q, want the value of the serve.db section", got)
+	}
+	if v.Get("serve") != nil {
+		t.Errorf("serve = %v, want the applied section removed", v.Get("serve"))
+	}
+	keys := v.AllKeys()
+	slices.Sort(keys)
+	if want := []string{"host", "name", "port"}; !slices.Equal(keys, want) {
+		t.Errorf("AllKeys = %v, want %v", keys, want)
+	}
+}
@@ -0,0 +1,285 @@
+package cobrax
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// skipConfigAnnotation is the annotation of the command for which RootPersistentPreRunE does not bind configs,
+// so that the command runs even if the config files are broken. The config commands reading or writing the files
+// have it, so that users can repair the files with them.
+const skipConfigAnnotation = "cobrax_skip_config"
+
+func configValidateCmd(fs afero.Fs, opts []ConfigOption) *cobra.Command {
+	validateCmd := &cobra.Command{}
+	validateCmd.Use = "validate [file...]"
+	validateCmd.Short = "Check the configuration files for syntax errors, invalid values and unknown keys"
+	validateCmd.Long = "Check the given configuration files, or all the discovered ones, without applying them.\n" +
+		"Every problem is printed as <file>[:<line>[:<column>]]: <message>, and the command fails if any is found."
+	validateCmd.Annotations = map[string]string{skipConfigAnnotation: "true"}
+	validateCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		opt := configCmdOptions(cmd, fs, opts)
+
+		files, err := validatedConfigFiles(opt, args)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			logger.Info("no config file found")
+			return nil
+		}
+		problems := validateConfigFiles(opt, cmd.Root(), files)
+		for _, p := range problems {
+			if _, err := fmt.Fprintln(cmd.OutOrStdout(), p); err != nil {
+				return err
+			}
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%d problems found in the configuration files", len(problems))
+		}
+		logger.Info(fmt.Sprintf("configuration files are valid: %s", strings.Join(files, ", ")))
+		return nil
+	}
+	return validateCmd
+}
+
+// validatedConfigFiles returns the files given as the arguments, the file given by the config flag,
+// or the discovered files of all the extensions.
+func validatedConfigFiles(opt *ConfigOptions, args []string) ([]string, error) {
+	if len(args) == 0 && opt.configFile != "" {
+		args = []string{opt.configFile}
+	}
+	if len(args) == 0 {
+		return discoverConfigFiles(opt), nil
+	}
+	files := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == StdinConfigFile {
+			files = append(files, arg)
+			continue
+		}
+		path, err := absPath(opt, arg)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// configProblem is a problem found in a config file. The line and the column are 0 when unknown.
+type configProblem struct {
+	path   string
+	line   int
+	column int
+	msg    string
+}
+
+func (p configProblem) String() string {
+	var b strings.Builder
+	b.WriteString(p.path)
+	if p.line > 0 {
+		fmt.Fprintf(&b, ":%d", p.line)
+		if p.column > 0 {
+			fmt.Fprintf(&b, ":%d", p.column)
+		}
+	}
+	b.WriteString(": " + p.msg)
+	return b.String()
+}
+
+// validateConfigFiles reads the files and the files they include, and returns the problems in the order of the files.
+func validateConfigFiles(opt *ConfigOptions, root *cobra.Command, files []string) []configProblem {
+	known := knownConfigKeys(root)
+	problems := make([]configProblem, 0)
+	for _, path := range files {
+		layers, err := readConfigLayers(opt, path, nil)
+		if err != nil {
+			problems = append(problems, configFileProblem(path, err))
+			continue
+		}
+		for _, layer := range layers {
+			problems = append(problems, checkConfigValues(opt, root, known, layer)...)
+		}
+	}
+	problems = append(problems, duplicateConfigProblems(opt, files)...)
+
+	// The same included file is reported once.
+	seen := make(map[configProblem]bool, len(problems))
+	unique := make([]configProblem, 0, len(problems))
+	for _, p := range problems {
+		if !seen[p] {
+			seen[p] = true
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
+
+// configFileProblem returns the problem of the file which cannot be read, at the position of the syntax error if known.
+func configFileProblem(path string, err error) configProblem {
+	var se *configSyntaxError
+	if !errors.As(err, &se) {
+		return configProblem{path: path, msg: err.Error()}
+	}
+	if se.path != "" {
+		path = se.path
+	}
+	return configProblem{path: path, line: se.line, column: se.column, msg: se.msg}
+}
+
+// checkConfigValues checks the values of the layer against the types of the flags, and reports the unknown keys.
+func checkConfigValues(opt *ConfigOptions, root *cobra.Command, known map[string]bool, layer configLayer) []configProblem {
+	keys := make([]string, 0)
+	vals := make(map[string]any)
+	flattenConfigMap("", layer.config, func(key string, val any) {
+		keys = append(keys, key)
+		vals[key] = val
+	})
+	slices.SortFunc(keys, sortConfigKey)
+	r := newRedactor(opt, root)
+	if isEncryptedConfigFile(layer.path) {
+		markEncryptedKeysSensitive(r, layer.config)
+	}
+
+	problems := make([]configProblem, 0)
+	for _, key := range keys {
+		name := profileValueKey(key)
+		if f := lookupConfigFlag(root, name); f != nil {
+			if !validConfigValue(opt, f, vals[key]) {
+				msg := fmt.Sprintf("invalid value %v for %s: expected %s", r.redact(key, vals[key]), key, f.Value.Type())
+				problems = append(problems, configProblem{path: layer.path, msg: msg})
+			}
+			continue
+		}
+		if !isKnownConfigKey(known, key) {
+			msg := fmt.Sprintf("%v: %s", ErrUnknownConfigKey, key)
+			if suggestion := suggestConfigKey(known, key); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+			problems = append(problems, configProblem{path: layer.path, msg: msg})
+			continue
+		}
+		// The key is under a known key, which must be a map flag.
+		for i := strings.LastIndex(name, "."); i > 0; i = strings.LastIndex(name, ".") {
+			name, key = name[:i], key[:len(key)-len(name)+i]
+			if f := lookupConfigFlag(root, name); f != nil {
+				if !strings.HasPrefix(f.Value.Type(), "stringTo") {
+					msg := fmt.Sprintf("invalid value for %s: expected %s, got a map", key, f.Value.Type())
+					problems = append(problems, configProblem{path: layer.path, msg: msg})
+				}
+				break
+			}
+		}
+	}
+	return problems
+}
+
+// validConfigValue reports whether the value of a config file can be set to the flag.
+// Secret and interpolation references are not checked, since they are resolved when binding.
+func validConfigValue(opt *ConfigOptions, f *pflag.Flag, val any) bool {
+	typ := f.Value.Type()
+	switch v := val.(type) {
+	case nil:
+		return true
+	case map[string]any:
+		return strings.HasPrefix(typ, "stringTo")
+	case string:
+		return validConfigString(opt, f, v)
+	}
+	if list, ok := toAnySlice(val); ok {
+		if !strings.HasSuffix(typ, "Slice") && !strings.HasSuffix(typ, "Array") {
+			return false
+		}
+		for _, e := range list {
+			if _, ok := e.(map[string]any); ok {
+				return false
+			}
+			if !validConfigString(opt, f, fmt.Sprint(e)) {
+				return false
+			}
+		}
+		return true
+	}
+	if strings.HasPrefix(typ, "stringTo") {
+		return false
+	}
+	_, err := parseFlagValue(f, fmt.Sprint(val))
+	return err == nil
+}
+
+// validConfigString reports whether the string value can be set to the flag, or is a reference resolved later.
+func validConfigString(opt *ConfigOptions, f *pflag.Flag, s string) bool {
+	if !opt.secretsDisabled {
+		if unescaped, ok := unescapeSecretReference(s); ok {
+			s = unescaped
+		} else if isSecretReference(s) {
+			return true
+		}
+	}
+	if !opt.interpolationDisabled && strings.Contains(s, "${") {
+		return true
+	}
+	_, err := parseFlagValue(f, s)
+	return err == nil
+}
+
+// duplicateConfigProblems reports the keys defined in both <name>.yaml and <name>.yml of the files,
+// since they are easily overlooked. The key is reported in the file overridden by the other one.
+func duplicateConfigProblems(opt *ConfigOptions, files []string) []configProblem {
+	problems := make([]configProblem, 0)
+	seen := make(map[string]bool)
+	for _, path := range files {
+		ext := filepath.Ext(path)
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		base := strings.TrimSuffix(path, ext)
+		if seen[base] {
+			continue
+		}
+		seen[base] = true
+
+		lower, higher := base+".yaml", base+".yml"
+		if slices.Index(opt.configFileExts, "yaml") > slices.Index(opt.configFileExts, "yml") {
+			lower, higher = higher, lower
+		}
+		lowerConfig, higherConfig := readExistingConfigFile(opt, lower), readExistingConfigFile(opt, higher)
+		if lowerConfig == nil || higherConfig == nil {
+			continue
+		}
+		defined := make(map[string]bool)
+		flattenConfigMap("", higherConfig, func(key string, _ any) { defined[key] = true })
+		keys := make([]string, 0)
+		flattenConfigMap("", lowerConfig, func(key string, _ any) {
+			if defined[key] {
+				keys = append(keys, key)
+			}
+		})
+		slices.SortFunc(keys, sortConfigKey)
+		for _, key := range keys {
+			problems = append(problems, configProblem{path: lower, msg: fmt.Sprintf("%s is also defined in %s, which overrides it", key, higher)})
+		}
+	}
+	return problems
+}
+
+// readExistingConfigFile returns the content of the config file, or nil if it does not exist or cannot be read.
+func readExistingConfigFile(opt *ConfigOptions, path string) map[string]any {
+	if exists, _ := afero.Exists(opt.fs, path); !exists {
+		return nil
+	}
+	m, err := readConfigFile(opt, path)
+	if err != nil {
+		logger.Debug(err.Error())
+		return nil
+	}
+	return m
+}
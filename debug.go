@@ -0,0 +1,105 @@
+package cobrax
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// DebugViperValue returns the config values of viper as a slog.LogValuer, which is resolved only when logged.
+// Dotted keys are nested groups ordered like DebugViper. When the sources are known,
+// each value is a group of "value" and "source". BindConfigs logs the text of DebugViper, and
+// applications log this value themselves to get the structured variant.
+//
+//	logger.Debug("config", "config", cobrax.DebugViperValue(v))
+func DebugViperValue(v *viper.Viper) slog.LogValuer {
+	return configLogValuer{v: v}
+}
+
+// DebugViperJSON returns the config values of viper as an indented JSON object in the same form as DebugViperValue.
+func DebugViperJSON(v *viper.Viper) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeLogValueJSON(&buf, DebugViperValue(v).LogValue()); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+type configLogValuer struct {
+	v *viper.Viper
+}
+
+func (c configLogValuer) LogValue() slog.Value {
+	keys := c.v.AllKeys()
+	slices.SortFunc(keys, sortConfigKey)
+	return slog.GroupValue(c.attrs("", keys)...)
+}
+
+// attrs returns the attributes of the sorted keys under the prefix, grouping the keys of the same section.
+func (c configLogValuer) attrs(prefix string, keys []string) []slog.Attr {
+	sources := Sources(c.v)
+	attrs := make([]slog.Attr, 0, len(keys))
+	for i := 0; i < len(keys); {
+		section, rest, nested := strings.Cut(keys[i], ".")
+		if !nested {
+			key := prefix + keys[i]
+			val := sources.redactor().redact(key, c.v.Get(key))
+			if sources != nil {
+				attrs = append(attrs, slog.Group(keys[i], slog.Any("value", val), slog.String("source", sources.Lookup(key).String())))
+			} else {
+				attrs = append(attrs, slog.Any(keys[i], val))
+			}
+			i++
+			continue
+		}
+		children := []string{rest}
+		for i++; i < len(keys); i++ {
+			s, r, ok := strings.Cut(keys[i], ".")
+			if !ok || s != section {
+				break
+			}
+			children = append(children, r)
+		}
+		attrs = append(attrs, slog.Attr{Key: section, Value: slog.GroupValue(c.attrs(prefix+section+".", children)...)})
+	}
+	return attrs
+}
+
+// writeLogValueJSON writes the value as JSON, keeping the order of the attributes of groups.
+func writeLogValueJSON(buf *bytes.Buffer, val slog.Value) error {
+	val = val.Resolve()
+	if val.Kind() != slog.KindGroup {
+		b, err := json.Marshal(val.Any())
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		return nil
+	}
+	buf.WriteByte('{')
+	for i, a := range val.Group() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(a.Key)
+		if err != nil {
+			return err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		if err := writeLogValueJSON(buf, a.Value); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
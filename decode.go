@@ -0,0 +1,228 @@
+package cobrax
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// Decode returns the struct T filled with the config values of viper.
+//
+// The key of a field is its `cobrax` tag, e.g. `cobrax:"listen-addr"`, or the field name in lower case, and "-" skips
+// the field. The fields of a nested struct are under the key of the struct, and the fields of an embedded struct
+// without the tag are at the same level. A key matches the values in both kebab-case and snake_case,
+// like the flag names normalized by SnakeToKebab or KebabToSnake.
+//
+// The `validate` tag lists the rules separated by commas: required, min=N, max=N, oneof=a b c, file-exists and
+// regex=PATTERN, which has to be the last one since the pattern may contain commas. min and max are the lengths of
+// strings, slices and maps. oneof, regex and file-exists accept the zero value, which is rejected only by required.
+// Only WithFs and WithEnvironment of the options are used, to check file-exists.
+//
+// All the invalid values are reported as *DecodeError, which names the file, flag or env var the value came from.
+func Decode[T any](v *viper.Viper, opts ...ConfigOption) (T, error) {
+	var cfg T
+	rv := reflect.ValueOf(&cfg).Elem()
+	if rv.Kind() != reflect.Struct {
+		return cfg, fmt.Errorf("cannot decode config into %T: not a struct", cfg)
+	}
+	opt := &ConfigOptions{fs: afero.NewOsFs(), env: osEnvironment{}}
+	for _, fn := range opts {
+		fn(opt)
+	}
+	d := &configDecoder{v: v, opt: opt, sources: Sources(v)}
+	d.decodeStruct(rv, "")
+	return cfg, errors.Join(d.errs...)
+}
+
+// DecodeError is the error of the config value of a field decoded by Decode.
+type DecodeError struct {
+	Key    string
+	Source Source // Layer the value came from, which is unknown if viper was not bound by BindConfigs
+	Err    error
+
+	sourceKnown bool
+}
+
+func (e *DecodeError) Error() string {
+	if !e.sourceKnown {
+		return fmt.Sprintf("%s: %v", e.Key, e.Err)
+	}
+	if e.Source.Kind == SourceDefault {
+		return fmt.Sprintf("%s: %v (default)", e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s: %v (set by %s)", e.Key, e.Err, e.Source)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+type configDecoder struct {
+	v       *viper.Viper
+	opt     *ConfigOptions
+	sources *ConfigSources
+	errs    []error
+}
+
+func (d *configDecoder) decodeStruct(rv reflect.Value, prefix string) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, tagged := field.Tag.Lookup("cobrax")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if !tagged {
+			name = strings.ToLower(field.Name)
+		}
+		if isConfigSection(field.Type) {
+			if field.Anonymous && !tagged {
+				d.decodeStruct(rv.Field(i), prefix)
+			} else {
+				d.decodeStruct(rv.Field(i), prefix+name+".")
+			}
+			continue
+		}
+
+		key := d.lookupKey(prefix + name)
+		if raw := d.v.Get(key); raw != nil {
+			if err := d.v.UnmarshalKey(key, rv.Field(i).Addr().Interface()); err != nil {
+				d.fail(key, fmt.Errorf("invalid value %v: expected %s", d.sources.redactor().redact(key, raw), field.Type))
+				continue
+			}
+		}
+		if rules, ok := field.Tag.Lookup("validate"); ok {
+			if err := d.validate(key, rv.Field(i), rules); err != nil {
+				d.fail(key, err)
+			}
+		}
+	}
+}
+
+// isConfigSection reports whether the fields of the type are decoded as the keys of a section.
+func isConfigSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{})
+}
+
+// lookupKey returns the key in kebab-case or snake_case which has a value, preferring the one set explicitly.
+func (d *configDecoder) lookupKey(key string) string {
+	keys := slices.Compact([]string{key, string(SnakeToKebab(nil, key)), string(KebabToSnake(nil, key))})
+	for _, k := range keys {
+		if d.v.IsSet(k) {
+			return k
+		}
+	}
+	for _, k := range keys {
+		if d.v.Get(k) != nil {
+			return k
+		}
+	}
+	return key
+}
+
+func (d *configDecoder) fail(key string, err error) {
+	e := &DecodeError{Key: key, Err: err}
+	if d.sources != nil {
+		e.Source, e.sourceKnown = d.sources.Lookup(key), true
+	}
+	d.errs = append(d.errs, e)
+}
+
+// validate checks the value with the rules of the validate tag.
+func (d *configDecoder) validate(key string, rv reflect.Value, rules string) error {
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			if slices.Contains(strings.Split(rules, ","), "required") {
+				return errors.New("is required")
+			}
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	for rules != "" {
+		var rule string
+		if strings.HasPrefix(rules, "regex=") {
+			rule, rules = rules, "" // The pattern may contain commas
+		} else {
+			rule, rules, _ = strings.Cut(rules, ",")
+		}
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			if rv.IsZero() {
+				return errors.New("is required")
+			}
+		case "min", "max":
+			n, limit, err := validationBound(rv, param)
+			if err != nil {
+				return fmt.Errorf("invalid %s rule: %w", name, err)
+			}
+			if name == "min" && n < limit {
+				return fmt.Errorf("must be at least %s", param)
+			}
+			if name == "max" && n > limit {
+				return fmt.Errorf("must be at most %s", param)
+			}
+		case "oneof":
+			if !rv.IsZero() && !slices.Contains(strings.Fields(param), fmt.Sprint(rv.Interface())) {
+				return fmt.Errorf("%q must be one of %s", fmt.Sprint(d.sources.redactor().redact(key, rv.Interface())), strings.Join(strings.Fields(param), ", "))
+			}
+		case "regex":
+			re, err := regexp.Compile(param)
+			if err != nil {
+				return fmt.Errorf("invalid regex rule: %w", err)
+			}
+			if !rv.IsZero() && !re.MatchString(fmt.Sprint(rv.Interface())) {
+				return fmt.Errorf("%q must match %s", fmt.Sprint(d.sources.redactor().redact(key, rv.Interface())), param)
+			}
+		case "file-exists":
+			if rv.Kind() != reflect.String || rv.String() == "" {
+				continue
+			}
+			path, err := absPath(d.opt, rv.String())
+			if err != nil {
+				return err
+			}
+			if exists, err := afero.Exists(d.opt.fs, path); err != nil {
+				return err
+			} else if !exists {
+				return fmt.Errorf("file does not exist: %s", path)
+			}
+		default:
+			return fmt.Errorf("unknown validation rule: %q", name)
+		}
+	}
+	return nil
+}
+
+// validationBound returns the number compared by the min and max rules, which is the length of
+// strings, slices and maps, and the limit parsed in the type of the value.
+func validationBound(rv reflect.Value, param string) (float64, float64, error) {
+	var n float64
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		n = float64(rv.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(rv.Int())
+		if rv.Type() == reflect.TypeOf(time.Duration(0)) {
+			limit, err := time.ParseDuration(param)
+			return n, float64(limit), err
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = rv.Float()
+	default:
+		return 0, 0, fmt.Errorf("not supported for %s", rv.Type())
+	}
+	limit, err := strconv.ParseFloat(param, 64)
+	return n, limit, err
+}
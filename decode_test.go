@@ -0,0 +1,103 @@
+package cobrax
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+type testDecodeConfig struct {
+	Name       string        `validate:"required"`
+	ListenAddr string        `cobrax:"listen-addr"`
+	Port       int           `validate:"min=1,max=65535"`
+	Mode       string        `validate:"oneof=dev prod"`
+	Tags       []string      `validate:"max=2"`
+	CertFile   string        `cobrax:"cert-file" validate:"file-exists"`
+	Timeout    time.Duration `validate:"max=1m"`
+	Token      string        `validate:"oneof=a b"`
+	Skipped    string        `cobrax:"-"`
+	Server     struct {
+		Host string `validate:"regex=^[a-z.]+$"`
+	}
+}
+
+func TestDecode(t *testing.T) {
+	files := map[string]string{
+		"/work/.app.yaml": "name: app\nlisten_addr: :80\nport: 80\nmode: dev\ntags: [a, b]\ncert-file: cert.pem\n" +
+			"timeout: 30s\nskipped: x\nserver:\n  host: example.com\n",
+		"/work/cert.pem": "cert",
+	}
+	v, err := bindTestConfig(t, files, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Decode[testDecodeConfig](v, WithFs(newTestFs(t, files)), WithEnvironment(newTestEnvironment(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "app" || cfg.ListenAddr != ":80" || cfg.Port != 80 || cfg.Mode != "dev" || len(cfg.Tags) != 2 ||
+		cfg.CertFile != "cert.pem" || cfg.Timeout != 30*time.Second || cfg.Skipped != "" || cfg.Server.Host != "example.com" {
+		t.Errorf("Decode = %+v", cfg)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	flags := pflag.NewFlagSet("app", pflag.ContinueOnError)
+	flags.Int("port", 80, "")
+	if err := flags.Parse([]string{"--port", "0"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_MODE", "test")
+
+	tests := []struct {
+		name    string
+		content string
+		opts    []ConfigOption
+		wantMsg string
+	}{
+		{"required", "port: 80\n", nil, "name: is required (default)"},
+		{"max", "name: app\nport: 70000\n", nil, "port: must be at most 65535 (set by file /work/.app.yaml)"},
+		{"max length", "name: app\nport: 80\ntags: [a, b, c]\n", nil, "tags: must be at most 2 (set by file /work/.app.yaml)"},
+		{"max duration", "name: app\nport: 80\ntimeout: 2m\n", nil, "timeout: must be at most 1m"},
+		{"oneof", "name: app\nport: 80\nmode: test\n", nil, `mode: "test" must be one of dev, prod (set by file /work/.app.yaml)`},
+		{"redacted", "name: app\nport: 80\ntoken: secret\n", nil, `token: "****" must be one of a, b`},
+		{"regex", "name: app\nport: 80\nserver:\n  host: Example!\n", nil, `server.host: "Example!" must match ^[a-z.]+$`},
+		{"file-exists", "name: app\nport: 80\ncert-file: missing.pem\n", nil, "cert-file: file does not exist: /work/missing.pem"},
+		{"type", "name: app\nport: abc\n", nil, "port: invalid value abc: expected int"},
+		{"flag", "name: app\n", []ConfigOption{WithFlags(flags)}, "port: must be at least 1 (set by flag --port)"},
+		{"env", "name: app\nport: 80\nmode: dev\n", []ConfigOption{WithEnvPrefix("APP")}, `mode: "test" must be one of dev, prod (set by env APP_MODE)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := bindLocalConfig(t, tt.content, nil, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Decode[testDecodeConfig](v, WithFs(newTestFs(t, nil)), WithEnvironment(newTestEnvironment(nil)))
+			var de *DecodeError
+			if !errors.As(err, &de) {
+				t.Fatalf("err = %v, want a DecodeError", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestDecodeWithoutSources(t *testing.T) {
+	v := viper.New()
+	v.Set("name", "app")
+	v.Set("port", 0)
+	_, err := Decode[testDecodeConfig](v)
+	if err == nil || err.Error() != "port: must be at least 1" {
+		t.Errorf("err = %v, want the error without the source", err)
+	}
+	if _, err := Decode[int](v); err == nil {
+		t.Error("expected an error decoding into a non-struct type")
+	}
+}
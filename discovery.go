@@ -0,0 +1,154 @@
+package cobrax
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// discoverConfigFiles returns the existing config files from the lowest precedence.
+func discoverConfigFiles(opt *ConfigOptions) []string {
+	files := make([]string, 0)
+	for _, cf := range configFileCandidates(opt) {
+		files = append(files, existingConfigFiles(opt, cf)...)
+	}
+	return files
+}
+
+// discoverFirstConfigFiles returns the existing config files in the order searched when merging is disabled,
+// where the first readable one is used: the user's paths in the configured order, then the system-wide paths.
+func discoverFirstConfigFiles(opt *ConfigOptions) []string {
+	files := make([]string, 0)
+	for _, paths := range [][]string{opt.configFilePaths, opt.systemConfigFilePaths} {
+		for _, p := range paths {
+			// The expanded paths are from the lowest precedence.
+			candidates := expandConfigFilePaths(opt, []string{p})
+			for i := len(candidates) - 1; i >= 0; i-- {
+				files = append(files, existingConfigFiles(opt, candidates[i])...)
+			}
+		}
+	}
+	return files
+}
+
+// existingConfigFiles returns the existing files of the config file path without extension from the lowest precedence.
+func existingConfigFiles(opt *ConfigOptions, cf string) []string {
+	files := make([]string, 0)
+	for _, ext := range opt.configFileExts {
+		// The encrypted file takes precedence over the plain one.
+		for _, name := range []string{cf, cf + encryptedConfigExt} {
+			name, err := absPath(opt, expandEnv(opt, fmt.Sprintf("%s.%s", name, ext)))
+			if err != nil {
+				logger.Debug(err.Error())
+				continue
+			}
+			if exists, _ := afero.Exists(opt.fs, name); !exists {
+				logger.Debug(fmt.Sprintf("config file not found: %s", name))
+				continue
+			}
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// configFileCandidates returns the system-wide and user's config file paths without extension from the lowest precedence.
+func configFileCandidates(opt *ConfigOptions) []string {
+	return append(expandConfigFilePaths(opt, opt.systemConfigFilePaths), userConfigFileCandidates(opt)...)
+}
+
+// userConfigFileCandidates returns the user's config file paths without extension from the lowest precedence.
+func userConfigFileCandidates(opt *ConfigOptions) []string {
+	return expandConfigFilePaths(opt, opt.configFilePaths)
+}
+
+// expandConfigFilePaths expands the paths into a path for each directory of $XDG_CONFIG_DIRS,
+// and the local path into the upward search paths.
+func expandConfigFilePaths(opt *ConfigOptions, paths []string) []string {
+	localPath := fmt.Sprintf("./.%s", opt.rootCmdName)
+	candidates := make([]string, 0, len(paths))
+	for _, p := range paths {
+		switch {
+		case opt.upwardSearch && p == localPath:
+			candidates = append(candidates, upwardConfigPaths(opt)...)
+		case strings.Contains(p, "$XDG_CONFIG_DIRS"):
+			// The first directory is the most important one.
+			dirs := filepath.SplitList(getenv(opt, "XDG_CONFIG_DIRS"))
+			for i := len(dirs) - 1; i >= 0; i-- {
+				if dirs[i] != "" {
+					candidates = append(candidates, strings.ReplaceAll(p, "$XDG_CONFIG_DIRS", dirs[i]))
+				}
+			}
+		default:
+			candidates = append(candidates, p)
+		}
+	}
+	return candidates
+}
+
+// systemConfigFilePaths returns the default system-wide config file paths without extension from the lowest precedence.
+func systemConfigFilePaths(app, goos string) []string {
+	if goos == "windows" {
+		return []string{fmt.Sprintf("$ProgramData/%s/config", app)}
+	}
+	return []string{
+		fmt.Sprintf("/etc/%s/config", app),
+		fmt.Sprintf("$XDG_CONFIG_DIRS/%s/config", app),
+	}
+}
+
+// userConfigFilePaths returns the default user's config file paths without extension from the lowest precedence.
+// The first one is written by the config commands when no config file exists.
+func userConfigFilePaths(app, goos string) []string {
+	paths := []string{fmt.Sprintf("%s/%s/config", userConfigDir(goos), app)}
+	if goos == "darwin" {
+		// os.UserConfigDir of macOS, while $XDG_CONFIG_HOME is kept for the tools following XDG on macOS.
+		paths = append(paths, fmt.Sprintf("$HOME/Library/Application Support/%s/config", app))
+	}
+	return append(paths, fmt.Sprintf("$HOME/.%s", app), fmt.Sprintf("./.%s", app))
+}
+
+// userConfigDir returns the user's config directory like os.UserConfigDir, which is not expanded yet.
+func userConfigDir(goos string) string {
+	if goos == "windows" {
+		return "$AppData"
+	}
+	return "$XDG_CONFIG_HOME"
+}
+
+// upwardConfigPaths returns "<dir>/.<app>" of the current directory and its parents up to the boundary,
+// from the farthest one.
+func upwardConfigPaths(opt *ConfigOptions) []string {
+	dir, err := opt.env.Getwd()
+	if err != nil {
+		logger.Debug(err.Error())
+		return []string{fmt.Sprintf("./.%s", opt.rootCmdName)}
+	}
+	paths := make([]string, 0)
+	for {
+		paths = append(paths, filepath.Join(dir, "."+opt.rootCmdName))
+		if hasAnyMarker(opt.fs, dir, opt.upwardMarkers) {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	slices.Reverse(paths)
+	logger.Debug(fmt.Sprintf("search config files upward: %s", strings.Join(paths, ", ")))
+	return paths
+}
+
+func hasAnyMarker(fs afero.Fs, dir string, markers []string) bool {
+	for _, m := range markers {
+		if exists, _ := afero.Exists(fs, filepath.Join(dir, m)); exists {
+			return true
+		}
+	}
+	return false
+}
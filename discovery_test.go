@@ -0,0 +1,123 @@
+package cobrax
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+var testConfigFiles = map[string]string{
+	"xdg":        "/home/u/.config/app/config.yaml",
+	"custom-xdg": "/cfg/app/config.yaml",
+	"home":       "/home/u/.app.yaml",
+	"home-json":  "/home/u/.app.json",
+	"local":      "/work/.app.yaml",
+	"system":     "/etc/app/config.yaml",
+	"xdg-dirs":   "/etc/xdg/app/config.yaml",
+}
+
+// testConfigFilesOf returns the files of testConfigFiles named by the names.
+// The "name" of each file is its name, and "loaded.<name>" is true.
+func testConfigFilesOf(names ...string) map[string]string {
+	files := make(map[string]string, len(names))
+	for _, name := range names {
+		path := testConfigFiles[name]
+		if strings.HasSuffix(path, ".json") {
+			files[path] = fmt.Sprintf(`{"name": %q, "loaded": {%q: true}}`, name, name)
+			continue
+		}
+		files[path] = fmt.Sprintf("name: %s\nloaded:\n  %s: true\n", name, name)
+	}
+	return files
+}
+
+func TestDiscoveryPrecedence(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the default paths differ on Windows")
+	}
+	tests := []struct {
+		name  string
+		files []string
+		vars  map[string]string
+		want  string
+	}{
+		{"local over home", []string{"xdg", "home", "local", "system"}, nil, "local"},
+		{"home over xdg", []string{"xdg", "home", "system"}, nil, "home"},
+		{"xdg over system", []string{"xdg", "system", "xdg-dirs"}, nil, "xdg"},
+		{"xdg dirs over etc", []string{"system", "xdg-dirs"}, nil, "xdg-dirs"},
+		{"yaml over json", []string{"home-json", "home"}, nil, "home"},
+		{"XDG_CONFIG_HOME", []string{"xdg", "custom-xdg"}, map[string]string{"XDG_CONFIG_HOME": "/cfg"}, "custom-xdg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := bindTestConfig(t, testConfigFilesOf(tt.files...), tt.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.GetString("name"); got != tt.want {
+				t.Errorf("name = %q, want the value of %s", got, tt.want)
+			}
+			for _, name := range tt.files {
+				if name == "xdg" && tt.vars["XDG_CONFIG_HOME"] != "" {
+					continue // not searched
+				}
+				if !v.GetBool("loaded." + name) {
+					t.Errorf("%s is not merged", name)
+				}
+			}
+		})
+	}
+}
+
+func TestDiscoveryUpwardSearch(t *testing.T) {
+	files := map[string]string{
+		"/.app.yaml":          "name: outside\nroot: true\n",
+		"/repo/.git/HEAD":     "ref: refs/heads/main\n",
+		"/repo/.app.yaml":     "name: repo\nrepo: true\n",
+		"/repo/sub/.app.yaml": "name: sub\n",
+	}
+	env := MapEnvironment{Vars: map[string]string{"HOME": "/home/u"}, Dir: "/repo/sub/dir"}
+	v, err := bindTestConfig(t, files, nil, WithEnvironment(env), WithUpwardSearch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetString("name"); got != "sub" {
+		t.Errorf("name = %q, want the value of the nearest file", got)
+	}
+	if !v.GetBool("repo") {
+		t.Error("the file at the repository root is not merged")
+	}
+	if v.IsSet("root") {
+		t.Error("the file above the repository root is merged")
+	}
+}
+
+func TestDiscoveryWithoutMerge(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the default paths differ on Windows")
+	}
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{"xdg first", []string{"xdg", "home", "local", "system"}, "xdg"},
+		{"home before local", []string{"home", "local", "system"}, "home"},
+		{"local before system", []string{"local", "system", "xdg-dirs"}, "local"},
+		{"system paths in the configured order", []string{"system", "xdg-dirs"}, "system"},
+		{"xdg-dirs", []string{"xdg-dirs"}, "xdg-dirs"},
+		{"json before yaml", []string{"home", "home-json"}, "home-json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := bindTestConfig(t, testConfigFilesOf(tt.files...), nil, WithMergeConfig(false))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.GetString("name"); got != tt.want {
+				t.Errorf("name = %q, want the value of %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package cobrax
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	encryptedConfigExt     = ".enc"
+	encryptedConfigPEMType = "COBRAX ENCRYPTED CONFIG"
+	encryptedConfigCipher  = "AES-256-GCM"
+	configKeySize          = 32
+	defaultConfigKeyName   = "config-key"
+)
+
+var (
+	ErrConfigKeyNotFound    = errors.New("key file to decrypt config files is not found")
+	ErrInvalidEncryptedFile = errors.New("invalid encrypted config file")
+)
+
+// isEncryptedConfigFile reports whether the file is an encrypted config file such as config.enc.yaml.
+func isEncryptedConfigFile(path string) bool {
+	return filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))) == encryptedConfigExt
+}
+
+// encryptedConfigPath returns the path of the encrypted file of the config file, e.g. config.enc.yaml for config.yaml.
+func encryptedConfigPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + encryptedConfigExt + ext
+}
+
+// configKeyEnvName returns the environment variable of the key file path, PREFIX_CONFIG_KEY by default.
+func configKeyEnvName(opt *ConfigOptions) string {
+	prefix := opt.envPrefix
+	if prefix == "" {
+		prefix = opt.rootCmdName
+	}
+	name := opt.keyFileFlagName
+	if name == "" {
+		name = defaultConfigKeyName
+	}
+	return EnvName(prefix, name)
+}
+
+// readConfigKey reads the base64 encoded key from the key file.
+func readConfigKey(opt *ConfigOptions) ([]byte, error) {
+	path := expandEnv(opt, opt.keyFile)
+	b, err := afero.ReadFile(opt.fs, path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s (set it by $%s)", ErrConfigKeyNotFound, path, configKeyEnvName(opt))
+	} else if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(key) != configKeySize {
+		return nil, fmt.Errorf("invalid key file: %s: must be %d bytes encoded in base64", path, configKeySize)
+	}
+	return key, nil
+}
+
+// readOrGenerateConfigKey reads the key file, or generates a new key and writes it when the file does not exist.
+func readOrGenerateConfigKey(opt *ConfigOptions) ([]byte, error) {
+	key, err := readConfigKey(opt)
+	if !errors.Is(err, ErrConfigKeyNotFound) {
+		return key, err
+	}
+	key = make([]byte, configKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	path := expandEnv(opt, opt.keyFile)
+	if err := opt.fs.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := afero.WriteFile(opt.fs, path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600); err != nil {
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("generated key file: %s", path))
+	return key, nil
+}
+
+// encryptConfig encrypts the content of a config file into a PEM block.
+func encryptConfig(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newConfigCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:    encryptedConfigPEMType,
+		Headers: map[string]string{"Cipher": encryptedConfigCipher},
+		Bytes:   gcm.Seal(nonce, nonce, plaintext, nil),
+	}), nil
+}
+
+// decryptConfig decrypts the PEM block written by encryptConfig.
+func decryptConfig(key, data []byte) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != encryptedConfigPEMType {
+		return nil, fmt.Errorf("%w: no %q block", ErrInvalidEncryptedFile, encryptedConfigPEMType)
+	}
+	if c := block.Headers["Cipher"]; c != encryptedConfigCipher {
+		return nil, fmt.Errorf("%w: unsupported cipher %q", ErrInvalidEncryptedFile, c)
+	}
+	gcm, err := newConfigCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(block.Bytes) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidEncryptedFile)
+	}
+	nonce, ciphertext := block.Bytes[:gcm.NonceSize()], block.Bytes[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrong key or modified content", ErrInvalidEncryptedFile)
+	}
+	return plaintext, nil
+}
+
+func newConfigCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// markEncryptedKeysSensitive marks every key of a decrypted config as sensitive,
+// since encrypted files are meant for credentials.
+func markEncryptedKeysSensitive(r *redactor, m map[string]any) {
+	flattenConfigMap("", m, func(key string, _ any) {
+		r.mark(key)
+	})
+}
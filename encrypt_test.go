@@ -0,0 +1,143 @@
+package cobrax
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+func testConfigKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, configKeySize)
+}
+
+func TestEncryptConfigRoundTrip(t *testing.T) {
+	key := testConfigKey(1)
+	plaintext := []byte("token: secret\n")
+	data, err := encryptConfig(key, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Error("the encrypted file contains the plaintext")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != encryptedConfigPEMType || block.Headers["Cipher"] != encryptedConfigCipher {
+		t.Fatalf("unexpected envelope: %s", data)
+	}
+
+	got, err := decryptConfig(key, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypted = %q, want %q", got, plaintext)
+	}
+
+	again, err := encryptConfig(key, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(data, again) {
+		t.Error("the same plaintext is encrypted into the same content, want a random nonce")
+	}
+}
+
+func TestDecryptConfigErrors(t *testing.T) {
+	key := testConfigKey(1)
+	data, err := encryptConfig(key, []byte("token: secret\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+
+	tampered := *block
+	tampered.Bytes = bytes.Clone(block.Bytes)
+	tampered.Bytes[len(tampered.Bytes)-1] ^= 1
+	otherCipher := *block
+	otherCipher.Headers = map[string]string{"Cipher": "AES-128-CBC"}
+	short := *block
+	short.Bytes = block.Bytes[:4]
+
+	tests := []struct {
+		name string
+		key  []byte
+		data []byte
+	}{
+		{"wrong key", testConfigKey(2), data},
+		{"modified content", key, pem.EncodeToMemory(&tampered)},
+		{"unsupported cipher", key, pem.EncodeToMemory(&otherCipher)},
+		{"too short", key, pem.EncodeToMemory(&short)},
+		{"other PEM type", key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: block.Bytes})},
+		{"not PEM", key, []byte("token: secret\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decryptConfig(tt.key, tt.data); !errors.Is(err, ErrInvalidEncryptedFile) {
+				t.Errorf("err = %v, want %v", err, ErrInvalidEncryptedFile)
+			}
+		})
+	}
+}
+
+func TestIsEncryptedConfigFile(t *testing.T) {
+	tests := map[string]bool{
+		"/home/u/.app.enc.yaml":               true,
+		"/home/u/.config/app/config.enc.toml": true,
+		"/home/u/.app.yaml":                   false,
+		"/home/u/.enc":                        false,
+	}
+	for path, want := range tests {
+		if got := isEncryptedConfigFile(path); got != want {
+			t.Errorf("isEncryptedConfigFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if got := encryptedConfigPath("/home/u/.app.yaml"); got != "/home/u/.app.enc.yaml" {
+		t.Errorf("encryptedConfigPath = %q", got)
+	}
+}
+
+func TestBindEncryptedConfig(t *testing.T) {
+	key := testConfigKey(3)
+	data, err := encryptConfig(key, []byte("name: app\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := bindTestConfig(t, map[string]string{
+		"/home/u/.config/app/config.key": base64.StdEncoding.EncodeToString(key) + "\n",
+		"/home/u/.app.enc.yaml":          string(data),
+		"/home/u/.app.yaml":              "name: plain\nport: 80\n",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetString("name"); got != "app" {
+		t.Errorf("name = %q, want the value of the encrypted file", got)
+	}
+	if got := v.GetInt("port"); got != 80 {
+		t.Errorf("port = %d, want the value of the plain file", got)
+	}
+	if !Sources(v).redactor().isSensitive("name") {
+		t.Error("name is not sensitive, want the keys of the encrypted file sensitive")
+	}
+
+	other, err := bindLocalConfig(t, "name: plain\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Sources(other).redactor().isSensitive("name") {
+		t.Error("name is sensitive in another config, want the keys marked only for the encrypted one")
+	}
+}
+
+func TestBindEncryptedConfigWithoutKey(t *testing.T) {
+	data, err := encryptConfig(testConfigKey(3), []byte("name: app\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = bindTestConfig(t, map[string]string{"/app.enc.yaml": string(data)}, nil, WithConfigFileName("/app.enc.yaml"))
+	if !errors.Is(err, ErrConfigKeyNotFound) {
+		t.Errorf("err = %v, want %v", err, ErrConfigKeyNotFound)
+	}
+}
@@ -0,0 +1,84 @@
+package cobrax
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// EnvName returns the name of the environment variable bound to the config key.
+func EnvName(prefix, key string) string {
+	if prefix != "" {
+		key = prefix + "_" + key
+	}
+	return strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// bindEnvs binds environment variables to every key known to v.
+// Keys are bound explicitly instead of using AutomaticEnv so that the variable of the sub-config takes precedence.
+func bindEnvs(v *viper.Viper, opt *ConfigOptions, sources *ConfigSources) {
+	v.SetEnvPrefix(opt.envPrefix)
+	v.SetEnvKeyReplacer(envKeyReplacer)
+	envs := make(map[string][]string)
+	for _, key := range v.AllKeys() {
+		names := []string{EnvName(opt.envPrefix, key)}
+		if !isSubConfigKey(opt, key) {
+			for _, sub := range opt.subConfigKeys {
+				names = append([]string{EnvName(opt.envPrefix, sub+"."+key)}, names...)
+			}
+		}
+		_ = v.BindEnv(append([]string{key}, names...)...)
+		envs[key] = names
+	}
+	sources.setEnvs(opt.envPrefix, envs)
+	logger.Debug(fmt.Sprintf("bind environment variables with prefix: %s", opt.envPrefix))
+}
+
+// isSubConfigKey reports whether the key is in the sub-config sections.
+func isSubConfigKey(opt *ConfigOptions, key string) bool {
+	for _, sub := range opt.subConfigKeys {
+		if key == sub || strings.HasPrefix(key, sub+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupEnv returns the first set variable in names, which are ordered from the highest precedence.
+func lookupEnv(names []string) (string, string, bool) {
+	for _, name := range names {
+		if val, ok := os.LookupEnv(name); ok && val != "" {
+			return name, val, true
+		}
+	}
+	return "", "", false
+}
+
+const envUsageAnnotation = "cobrax_env_usage"
+
+// setEnvUsage appends the name of the bound environment variable to the usage of each flag of the command.
+func setEnvUsage(cmd *cobra.Command, prefix string, skip ...string) {
+	fn := func(f *pflag.Flag) {
+		if _, ok := f.Annotations[envUsageAnnotation]; ok {
+			return
+		}
+		if f.Name == "help" || f.Name == "version" || slices.Contains(skip, f.Name) {
+			return
+		}
+		if f.Annotations == nil {
+			f.Annotations = make(map[string][]string)
+		}
+		env := EnvName(prefix, f.Name)
+		f.Annotations[envUsageAnnotation] = []string{env}
+		f.Usage = strings.TrimSpace(fmt.Sprintf("%s [$%s]", f.Usage, env))
+	}
+	cmd.LocalFlags().VisitAll(fn)
+	cmd.InheritedFlags().VisitAll(fn)
+}
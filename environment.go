@@ -0,0 +1,73 @@
+package cobrax
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Environment is the process environment read to discover config files: environment variables and
+// the working directory. Replace it together with the Fs to discover config files hermetically.
+type Environment interface {
+	LookupEnv(key string) (string, bool)
+	Getwd() (string, error)
+}
+
+type osEnvironment struct{}
+
+func (osEnvironment) LookupEnv(key string) (string, bool) { return os.LookupEnv(key) }
+func (osEnvironment) Getwd() (string, error)              { return os.Getwd() }
+
+// MapEnvironment is an Environment with fixed variables and working directory, e.g. for tests with afero.MemMapFs.
+type MapEnvironment struct {
+	Vars map[string]string
+	Dir  string // Working directory
+}
+
+func (e MapEnvironment) LookupEnv(key string) (string, bool) {
+	val, ok := e.Vars[key]
+	return val, ok
+}
+
+func (e MapEnvironment) Getwd() (string, error) {
+	if e.Dir == "" {
+		return "", errors.New("working directory is not set")
+	}
+	return e.Dir, nil
+}
+
+// getenv returns the environment variable. XDG_CONFIG_HOME and XDG_CONFIG_DIRS default to $HOME/.config
+// and /etc/xdg as the XDG spec defines, and HOME defaults to USERPROFILE on Windows.
+func getenv(opt *ConfigOptions, key string) string {
+	val, _ := opt.env.LookupEnv(key)
+	if val != "" {
+		return val
+	}
+	switch {
+	case key == "XDG_CONFIG_HOME":
+		return expandEnv(opt, "$HOME/.config")
+	case key == "XDG_CONFIG_DIRS":
+		return "/etc/xdg"
+	case key == "HOME" && runtime.GOOS == "windows":
+		val, _ = opt.env.LookupEnv("USERPROFILE")
+	}
+	return val
+}
+
+// expandEnv replaces ${var} or $var in the string like os.ExpandEnv, with the Environment of opt.
+func expandEnv(opt *ConfigOptions, s string) string {
+	return os.Expand(s, func(key string) string { return getenv(opt, key) })
+}
+
+// absPath returns the absolute path like filepath.Abs, with the working directory of the Environment of opt.
+func absPath(opt *ConfigOptions, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+	wd, err := opt.env.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(wd, path), nil
+}
@@ -0,0 +1,154 @@
+package cobrax
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var ErrConfigNotBound = errors.New("config is not bound")
+
+// ExplainConfigCmd returns the command explaining the value of a key for a command: "<name> [command...] <key>".
+// The key may also have the sections of the command, e.g. "explain-config serve port" or "explain-config serve.port".
+func ExplainConfigCmd(name string, v *viper.Viper) *cobra.Command {
+	explainCmd := &cobra.Command{}
+	explainCmd.Use = name + " [command...] <key>"
+	explainCmd.Short = "Show every value of the configuration key and which one is used by the command"
+	explainCmd.Args = cobra.MinimumNArgs(1)
+	explainCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return ExplainConfig(cmd.OutOrStdout(), v, cmd.Root(), strings.Join(args, "."))
+	}
+	return explainCmd
+}
+
+// ExplainConfig writes every value of the dotted key found across the layers and marks the one used.
+// The leading sections of the key naming subcommands of root select the command, e.g. "serve.port" is the key "port"
+// of "serve", and the sub-config sections and the flag of that command are evaluated as if it were running.
+func ExplainConfig(w io.Writer, v *viper.Viper, root *cobra.Command, key string) error {
+	sources := Sources(v)
+	if sources == nil {
+		return ErrConfigNotBound
+	}
+	target, name := explainTarget(root, strings.ToLower(key))
+	candidates := sources.commandCandidates(target, name)
+	if len(candidates) == 0 {
+		return fmt.Errorf("no value found for key %q", key)
+	}
+	// The candidates are ordered by precedence.
+	winner := candidates[len(candidates)-1]
+	value := sources.redactor().redact(name, effectiveValue(v, sources, name, winner))
+
+	if _, err := fmt.Fprintf(w, "%s: %v\n", key, value); err != nil {
+		return err
+	}
+	for i, c := range candidates {
+		mark := " "
+		val := c.Value
+		if i == len(candidates)-1 {
+			mark, val = "*", value
+		} else if s, ok := val.(string); !ok || !isSecretReference(s) {
+			val = sources.redactor().redact(name, val)
+		}
+		if _, err := fmt.Fprintf(w, "  %s %s: %v\n", mark, c.Source, val); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "used %s: %s\n", winner.Source, precedenceReason(winner.Source, candidates))
+	return err
+}
+
+// effectiveValue returns the value of the key in viper, with interpolation and secret references resolved,
+// when viper got it from the winner. The winner of another command than the running one is returned as written.
+func effectiveValue(v *viper.Viper, sources *ConfigSources, key string, winner Candidate) any {
+	if sources.Lookup(key) != winner.Source || !v.IsSet(key) {
+		return winner.Value
+	}
+	return v.Get(key)
+}
+
+// explainTarget returns the command selected by the leading sections of the key and the rest of the key.
+func explainTarget(root *cobra.Command, key string) (*cobra.Command, string) {
+	cmd := root
+	for {
+		section, rest, ok := strings.Cut(key, ".")
+		if !ok {
+			return cmd, key
+		}
+		sub := findSubCommand(cmd, section)
+		if sub == nil {
+			return cmd, key
+		}
+		cmd, key = sub, rest
+	}
+}
+
+// commandCandidates returns the values of the key for the command from the lowest precedence:
+// the default of the flag, config files and the profile, the sub-config sections of the command,
+// environment variables and the flag given on the command line.
+// The sub-config sections of the running command are replaced with the ones of the command.
+func (s *ConfigSources) commandCandidates(cmd *cobra.Command, key string) []Candidate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	candidates := make([]Candidate, 0)
+	f := cmd.Flag(key)
+	if f != nil {
+		candidates = append(candidates, Candidate{Source: Source{Kind: SourceDefault}, Value: f.DefValue})
+	}
+	fileCandidates := func(key string) []Candidate {
+		return slices.DeleteFunc(slices.Clone(s.candidates[key]), func(c Candidate) bool { return c.Source.Kind == SourceOverride })
+	}
+	candidates = append(candidates, fileCandidates(key)...)
+	sections := commandConfigKeys(cmd)
+	for _, section := range sections {
+		if cs := fileCandidates(section + "." + key); len(cs) > 0 {
+			candidates = append(candidates, Candidate{Source: Source{Kind: SourceOverride, Name: section}, Value: cs[len(cs)-1].Value})
+		}
+	}
+	if s.envs != nil {
+		names := []string{EnvName(s.envPrefix, key)}
+		for _, section := range sections {
+			names = append(names, EnvName(s.envPrefix, section+"."+key))
+		}
+		for _, name := range names {
+			if name, val, ok := lookupEnv([]string{name}); ok {
+				candidates = append(candidates, Candidate{Source: Source{Kind: SourceEnv, Name: name}, Value: val})
+			}
+		}
+	}
+	// Only the flags shared with the running command can be given on the command line.
+	if f != nil && f.Changed && s.flags != nil && s.flags.Lookup(f.Name) == f {
+		candidates = append(candidates, Candidate{Source: Source{Kind: SourceFlag, Name: f.Name}, Value: f.Value.String()})
+	}
+	return candidates
+}
+
+func precedenceReason(winner Source, candidates []Candidate) string {
+	switch winner.Kind {
+	case SourceFlag:
+		return "command-line flags take precedence over every other layer"
+	case SourceEnv:
+		return "environment variables take precedence over config files and defaults"
+	case SourceOverride:
+		return "the sub-config section of the command takes precedence over the profile and top-level values of config files"
+	case SourceProfile:
+		return "the selected profile takes precedence over top-level values of config files"
+	case SourceFile:
+		files := 0
+		for _, c := range candidates {
+			if c.Source.Kind == SourceFile {
+				files++
+			}
+		}
+		if files > 1 {
+			return "config files loaded later take precedence over earlier ones"
+		}
+		return "config files take precedence over defaults"
+	default:
+		return "no other layer sets the key"
+	}
+}
@@ -0,0 +1,59 @@
+package cobrax
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestExplainConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		key   string
+		want  []string
+	}{
+		{
+			"interpolation",
+			map[string]string{"/work/.app.yaml": "port: ${PORT:-8080}\n"},
+			"port",
+			[]string{"port: 8080", "  * file /work/.app.yaml: 8080"},
+		},
+		{
+			"secret reference",
+			map[string]string{"/work/.app.yaml": "token: file:///run/token\n", "/run/token": "s3cret\n"},
+			"token",
+			[]string{"token: ****", "  * file /work/.app.yaml: ****"},
+		},
+		{
+			"section of another command",
+			map[string]string{"/work/.app.yaml": "serve:\n  port: ${PORT:-8080}\n"},
+			"serve.port",
+			[]string{"serve.port: ${PORT:-8080}", "  * override serve: ${PORT:-8080}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &cobra.Command{Use: "app"}
+			root.AddCommand(&cobra.Command{Use: "serve"})
+			v, err := bindTestConfig(t, tt.files, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := ExplainConfig(&buf, v, root, tt.key); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want+"\n") {
+					t.Errorf("output does not contain %q:\n%s", want, buf.String())
+				}
+			}
+			if strings.Contains(buf.String(), "s3cret") {
+				t.Errorf("output contains the secret:\n%s", buf.String())
+			}
+		})
+	}
+}
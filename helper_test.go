@@ -0,0 +1,43 @@
+package cobrax
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// newTestFs returns an in-memory Fs with the files of the paths and contents.
+func newTestFs(t *testing.T, files map[string]string) afero.Fs {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	for path, content := range files {
+		if err := afero.WriteFile(fs, path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+// newTestEnvironment returns the environment of the variables, where HOME is /home/u and the working directory is /work.
+func newTestEnvironment(vars map[string]string) MapEnvironment {
+	env := MapEnvironment{Vars: map[string]string{"HOME": "/home/u"}, Dir: "/work"}
+	for k, val := range vars {
+		env.Vars[k] = val
+	}
+	return env
+}
+
+// bindTestConfig binds the config files to a new viper, in an in-memory Fs and the environment of newTestEnvironment.
+func bindTestConfig(t *testing.T, files map[string]string, vars map[string]string, opts ...ConfigOption) (*viper.Viper, error) {
+	t.Helper()
+	v := viper.New()
+	opts = append([]ConfigOption{WithFs(newTestFs(t, files)), WithEnvironment(newTestEnvironment(vars))}, opts...)
+	return v, BindConfigs(v, "app", opts...)
+}
+
+// bindLocalConfig binds the content of ./.app.yaml like bindTestConfig.
+func bindLocalConfig(t *testing.T, content string, vars map[string]string, opts ...ConfigOption) (*viper.Viper, error) {
+	t.Helper()
+	return bindTestConfig(t, map[string]string{"/work/.app.yaml": content}, vars, opts...)
+}
@@ -0,0 +1,90 @@
+package cobrax
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// IncludeKey is the config key listing the files to be merged before the file declaring it.
+const IncludeKey = "include"
+
+var ErrIncludeCycle = errors.New("include cycle")
+
+// configLayer is the content of a config file.
+type configLayer struct {
+	path   string
+	config map[string]any
+}
+
+// readConfigLayers reads the config file and the files it includes recursively.
+// The layers are ordered from the lowest precedence: included files in declared order, then the including file.
+func readConfigLayers(opt *ConfigOptions, path string, chain []string) ([]configLayer, error) {
+	if i := slices.Index(chain, path); i >= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(append(slices.Clone(chain[i:]), path), " -> "))
+	}
+	chain = append(slices.Clip(chain), path)
+
+	m, err := readConfigFile(opt, path)
+	if err != nil {
+		return nil, err
+	}
+	if m, _, err = migrateConfigVersion(opt, path, m); err != nil {
+		return nil, err
+	}
+	includes, err := includePaths(opt, path, m[IncludeKey])
+	if err != nil {
+		return nil, err
+	}
+	delete(m, IncludeKey)
+	if deprecated := migrateKeys(opt, m); len(deprecated) > 0 {
+		logger.Warn(fmt.Sprintf("config file %s uses deprecated keys: %s", path, strings.Join(deprecated, ", ")))
+	}
+
+	layers := make([]configLayer, 0, len(includes)+1)
+	for _, inc := range includes {
+		incLayers, err := readConfigLayers(opt, inc, chain)
+		if errors.Is(err, ErrIncludeCycle) {
+			return nil, err
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to include %s from %s: %w", inc, path, err)
+		}
+		logger.Debug(fmt.Sprintf("included config file: %s from %s", inc, path))
+		layers = append(layers, incLayers...)
+	}
+	return append(layers, configLayer{path: path, config: m}), nil
+}
+
+// includePaths returns the paths declared by the include key, resolved relative to the directory of the including file.
+func includePaths(opt *ConfigOptions, path string, val any) ([]string, error) {
+	var paths []string
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		paths = []string{v}
+	case []any:
+		for _, p := range v {
+			s, ok := p.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s must be a list of file paths: %v", path, IncludeKey, val)
+			}
+			paths = append(paths, s)
+		}
+	case []string:
+		paths = v
+	default:
+		return nil, fmt.Errorf("%s: %s must be a list of file paths: %v", path, IncludeKey, val)
+	}
+
+	for i, p := range paths {
+		p = expandEnv(opt, p)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(path), p)
+		}
+		paths[i] = filepath.Clean(p)
+	}
+	return paths, nil
+}
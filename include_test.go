@@ -0,0 +1,114 @@
+package cobrax
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIncludes(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		key   string
+		want  string
+	}{
+		{
+			"declared order",
+			map[string]string{
+				"/work/.app.yaml": "include: [base.yaml, ../team.toml]\nname: app\n",
+				"/work/base.yaml": "name: base\nport: 80\nlevel: base\n",
+				"/team.toml":      "level = \"team\"\n",
+			},
+			"level", "team",
+		},
+		{
+			"including file over included ones",
+			map[string]string{
+				"/work/.app.yaml": "include: base.yaml\nname: app\n",
+				"/work/base.yaml": "name: base\n",
+			},
+			"name", "app",
+		},
+		{
+			"relative to the including file",
+			map[string]string{
+				"/work/.app.yaml":   "include: [conf/a.yaml]\n",
+				"/work/conf/a.yaml": "include: [b.yaml]\n",
+				"/work/conf/b.yaml": "name: b\n",
+				"/work/b.yaml":      "name: wrong\n",
+			},
+			"name", "b",
+		},
+		{
+			"environment variable",
+			map[string]string{
+				"/work/.app.yaml":     "include: ${HOME}/shared.json\n",
+				"/home/u/shared.json": `{"name": "shared"}`,
+			},
+			"name", "shared",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := bindTestConfig(t, tt.files, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.GetString(tt.key); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+			}
+			if v.IsSet(IncludeKey) {
+				t.Errorf("%s is set, want it removed", IncludeKey)
+			}
+		})
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr error
+		wantMsg string
+	}{
+		{
+			"cycle",
+			map[string]string{
+				"/work/.app.yaml": "include: [a.yaml]\n",
+				"/work/a.yaml":    "include: [b.yaml]\n",
+				"/work/b.yaml":    "include: [a.yaml]\n",
+			},
+			ErrIncludeCycle, "/work/a.yaml -> /work/b.yaml -> /work/a.yaml",
+		},
+		{
+			"self",
+			map[string]string{"/work/.app.yaml": "include: [.app.yaml]\n"},
+			ErrIncludeCycle, "/work/.app.yaml -> /work/.app.yaml",
+		},
+		{
+			"missing file",
+			map[string]string{"/work/.app.yaml": "include: [missing.yaml]\n"},
+			nil, "failed to include /work/missing.yaml from /work/.app.yaml",
+		},
+		{
+			"not a path",
+			map[string]string{"/work/.app.yaml": "include: [1]\n"},
+			nil, "include must be a list of file paths",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bindTestConfig(t, tt.files, nil, WithConfigFileName("/work/.app.yaml"))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package cobrax
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrInterpolationCycle = errors.New("interpolation cycle")
+
+// interpolateConfig expands references in string values of the merged config.
+//
+//	${ENV_VAR}          the value of the environment variable
+//	${ENV_VAR:-default} the default when the variable is unset or empty
+//	${other.key}        the value of the dotted config key
+//	${.key}             the value of the top-level config key
+//	$${...}             the literal "${...}"
+//
+// A name without a dot is always an environment variable, so that "port: ${PORT:-8080}" does not refer to itself.
+func interpolateConfig(loaded *loadedConfig, opt *ConfigOptions) error {
+	raw := make(map[string]any)
+	mergeConfigMaps(raw, loaded.config)
+	ip := &interpolator{env: opt.env, raw: raw, inactive: inactiveConfigSections(opt), secretKeys: loaded.secretKeys, redactor: loaded.redactor, expanded: make(map[string]string)}
+	return ip.interpolateMap("", loaded.config)
+}
+
+type interpolator struct {
+	env        Environment
+	raw        map[string]any  // config before expansion
+	inactive   map[string]bool // sections left unexpanded
+	secretKeys map[string]bool // keys of resolved secrets, which are not expanded
+	redactor   *redactor
+	expanded   map[string]string // expanded values of keys
+	resolving  []string          // keys being expanded, for cycle detection
+}
+
+func (ip *interpolator) interpolateMap(prefix string, m map[string]any) error {
+	for k, val := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if ip.inactive[key] {
+			continue
+		}
+		switch v := val.(type) {
+		case map[string]any:
+			if err := ip.interpolateMap(key, v); err != nil {
+				return err
+			}
+		case []any:
+			if ip.secretKeys[key] {
+				continue
+			}
+			expanded := make([]any, len(v))
+			for i, e := range v {
+				expanded[i] = e
+				if s, ok := e.(string); ok {
+					var err error
+					if expanded[i], err = ip.expand(key, s); err != nil {
+						return err
+					}
+				}
+			}
+			m[k] = expanded
+		case string:
+			expanded, err := ip.expandKey(key, v)
+			if err != nil {
+				return err
+			}
+			m[k] = expanded
+		}
+	}
+	return nil
+}
+
+// expandKey expands the string value of the key once and memorizes the result.
+func (ip *interpolator) expandKey(key, s string) (string, error) {
+	if expanded, ok := ip.expanded[key]; ok {
+		return expanded, nil
+	}
+	if ip.secretKeys[key] {
+		return s, nil // resolved secrets are not expanded
+	}
+	if i := slices.Index(ip.resolving, key); i >= 0 {
+		return "", fmt.Errorf("%w: %s", ErrInterpolationCycle, strings.Join(append(slices.Clone(ip.resolving[i:]), key), " -> "))
+	}
+	ip.resolving = append(ip.resolving, key)
+	expanded, err := ip.expand(key, s)
+	ip.resolving = ip.resolving[:len(ip.resolving)-1]
+	if err != nil {
+		return "", err
+	}
+	if expanded != s {
+		logger.Debug(fmt.Sprintf("interpolate %s: %v -> %v", key, ip.redactor.redact(key, s), ip.redactor.redact(key, expanded)))
+	}
+	ip.expanded[key] = expanded
+	return expanded, nil
+}
+
+func (ip *interpolator) expand(key, s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.HasPrefix(s[i:], "$${") {
+			b.WriteString("${")
+			i += 2
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			b.WriteByte(s[i])
+			continue
+		}
+		end := matchingBrace(s, i+2)
+		if end < 0 {
+			return "", fmt.Errorf("%s: unclosed reference: %s", key, s[i:])
+		}
+		val, err := ip.reference(key, s[i+2:end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(val)
+		i = end
+	}
+	return b.String(), nil
+}
+
+// reference returns the value of the reference "name" or "name:-default".
+func (ip *interpolator) reference(key, ref string) (string, error) {
+	name, def, hasDef := strings.Cut(ref, ":-")
+	val, ok, err := ip.lookup(key, name)
+	if err != nil {
+		return "", err
+	}
+	if (!ok || val == "") && hasDef {
+		return ip.expand(key, def)
+	}
+	if !ok {
+		logger.Debug(fmt.Sprintf("%s: reference ${%s} is not set", key, name))
+	}
+	return val, nil
+}
+
+// lookup returns the value of the config key if the name is dotted, or of the environment variable otherwise.
+func (ip *interpolator) lookup(key, name string) (string, bool, error) {
+	if !strings.Contains(name, ".") {
+		val, ok := ip.env.LookupEnv(name)
+		return val, ok, nil
+	}
+	refKey := strings.ToLower(strings.TrimPrefix(name, "."))
+	val, ok := configValueAt(ip.raw, refKey)
+	if !ok {
+		return "", false, nil
+	}
+	if ip.redactor.isSensitive(refKey) {
+		ip.redactor.mark(key)
+	}
+	if s, ok := val.(string); ok {
+		expanded, err := ip.expandKey(refKey, s)
+		return expanded, true, err
+	}
+	return fmt.Sprint(val), true, nil
+}
+
+// matchingBrace returns the index of "}" closing the reference starting at start, or -1.
+func matchingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// configValueAt returns the value at the dotted key of the nested map.
+func configValueAt(m map[string]any, key string) (any, bool) {
+	parent, last := "", key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		parent, last = key[:i], key[i+1:]
+	}
+	if parent != "" {
+		if m = configMapAt(m, parent); m == nil {
+			return nil, false
+		}
+	}
+	val, ok := m[last]
+	return val, ok
+}
@@ -0,0 +1,112 @@
+package cobrax
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestInterpolation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		vars    map[string]string
+		key     string
+		want    string
+	}{
+		{"env", "url: http://${HOST}", map[string]string{"HOST": "example.com"}, "url", "http://example.com"},
+		{"unset env", "url: http://${HOST}", nil, "url", "http://"},
+		{"default", "port: ${PORT:-8080}", nil, "port", "8080"},
+		{"default of empty env", "port: ${PORT:-8080}", map[string]string{"PORT": ""}, "port", "8080"},
+		{"env over default", "port: ${PORT:-8080}", map[string]string{"PORT": "9090"}, "port", "9090"},
+		{"env named like a key", "port: 80\nurl: ${PORT}", map[string]string{"PORT": "9090"}, "url", "9090"},
+		{"nested default", "dir: ${XDG_CACHE_HOME:-${HOME}/.cache}/app", nil, "dir", "/home/u/.cache/app"},
+		{"dotted key", "server:\n  host: localhost\nurl: http://${server.host}", nil, "url", "http://localhost"},
+		{"top-level key", "name: app\ndir: /var/${.name}", nil, "dir", "/var/app"},
+		{"key case", "server:\n  host: localhost\nurl: ${Server.Host}", nil, "url", "localhost"},
+		{"non-string key", "port: 80\nurl: :${.port}", nil, "url", ":80"},
+		{"chained keys", "a: ${.b}/a\nb: ${.c}/b\nc: c", nil, "a", "c/b/a"},
+		{"key referring to env", "port: ${PORT:-8080}\nurl: :${.port}", nil, "url", ":8080"},
+		{"unset key", "url: ${server.host:-localhost}", nil, "url", "localhost"},
+		{"escape", "tmpl: $${HOST}", map[string]string{"HOST": "example.com"}, "tmpl", "${HOST}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := bindLocalConfig(t, tt.content, tt.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.GetString(tt.key); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolationList(t *testing.T) {
+	v, err := bindLocalConfig(t, "hosts: [\"${HOST}\", b]", map[string]string{"HOST": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetStringSlice("hosts"); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("hosts = %q, want [a b]", got)
+	}
+}
+
+func TestInterpolationOfSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		vars    map[string]string
+		key     string
+		want    string
+	}{
+		{"sensitive key", "db:\n  password: ${DB_PASSWORD}", map[string]string{"DB_PASSWORD": "pw"}, "db.password", "pw"},
+		{"resolved secret", "token: env://TOKEN", map[string]string{"TOKEN": "a${HOST}"}, "token", "a${HOST}"},
+		{"reference to resolved secret", "token: env://TOKEN\nauth: Bearer ${.token}", map[string]string{"TOKEN": "a${HOST}"}, "auth", "Bearer a${HOST}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := bindLocalConfig(t, tt.content, tt.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.GetString(tt.key); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{"cycle", "a: ${.b}\nb: ${.a}", ErrInterpolationCycle},
+		{"self", "a: x${.a}", ErrInterpolationCycle},
+		{"unclosed", "a: ${HOST", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bindLocalConfig(t, tt.content, nil)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInterpolationDisabled(t *testing.T) {
+	v, err := bindLocalConfig(t, "port: ${PORT:-8080}", nil, WithInterpolationDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetString("port"); got != "${PORT:-8080}" {
+		t.Errorf("port = %q, want the literal value", got)
+	}
+}
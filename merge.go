@@ -0,0 +1,157 @@
+package cobrax
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// MergeStrategy is how the value of a config layer is merged into the value of the layers with lower precedence.
+type MergeStrategy int
+
+const (
+	MergeDeep         MergeStrategy = iota // Merge maps recursively and replace other values (default)
+	MergeReplace                           // Replace the value, even if it is a map
+	MergeAppend                            // Append the list to the list of lower layers
+	MergePrepend                           // Prepend the list to the list of lower layers
+	MergeUniqueAppend                      // Append the items of the list not in the list of lower layers
+)
+
+func (s MergeStrategy) String() string {
+	switch s {
+	case MergeReplace:
+		return "replace"
+	case MergeAppend:
+		return "append"
+	case MergePrepend:
+		return "prepend"
+	case MergeUniqueAppend:
+		return "unique-append"
+	default:
+		return "deep-merge"
+	}
+}
+
+// ParseMergeStrategy returns the MergeStrategy of the name printed by MergeStrategy.String.
+func ParseMergeStrategy(name string) (MergeStrategy, error) {
+	for _, s := range []MergeStrategy{MergeDeep, MergeReplace, MergeAppend, MergePrepend, MergeUniqueAppend} {
+		if s.String() == strings.ToLower(name) {
+			return s, nil
+		}
+	}
+	return MergeDeep, fmt.Errorf("unknown merge strategy: %q", name)
+}
+
+const mergeStrategyAnnotation = "cobrax_merge_strategy"
+
+// MarkFlagMergeStrategy makes the values of the slice flag merged into the config value with the strategy,
+// instead of replacing it. Only MergeAppend, MergePrepend and MergeUniqueAppend are meaningful for flags.
+func MarkFlagMergeStrategy(flags *pflag.FlagSet, name string, strategy MergeStrategy) error {
+	f := flags.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("flag %q does not exist", name)
+	}
+	if _, ok := f.Value.(pflag.SliceValue); !ok {
+		return fmt.Errorf("flag %q is not a slice flag", name)
+	}
+	return flags.SetAnnotation(name, mergeStrategyAnnotation, []string{strategy.String()})
+}
+
+// mergeConfigMapsWith merges src into dst like mergeConfigMaps, with the strategies of the dotted keys.
+func mergeConfigMapsWith(dst, src map[string]any, prefix string, strategies map[string]MergeStrategy) {
+	for k, sv := range src {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		strategy := strategies[key]
+		if strategy == MergeDeep {
+			if sm, ok := sv.(map[string]any); ok {
+				dm, ok := dst[k].(map[string]any)
+				if !ok {
+					dm = make(map[string]any, len(sm))
+					dst[k] = dm
+				}
+				mergeConfigMapsWith(dm, sm, key, strategies)
+				continue
+			}
+		}
+		dst[k] = mergeConfigValue(dst[k], sv, strategy)
+	}
+}
+
+// mergeConfigValue returns the value of the higher layer merged into the value of the lower layer.
+// Values which are not lists are replaced, even with the list strategies.
+func mergeConfigValue(lower, higher any, strategy MergeStrategy) any {
+	if sm, ok := higher.(map[string]any); ok {
+		m := make(map[string]any, len(sm))
+		mergeConfigMaps(m, sm)
+		return m
+	}
+	dl, dok := toAnySlice(lower)
+	sl, sok := toAnySlice(higher)
+	if !dok || !sok {
+		return higher
+	}
+	switch strategy {
+	case MergeAppend:
+		return append(slices.Clip(dl), sl...)
+	case MergePrepend:
+		return append(slices.Clip(sl), dl...)
+	case MergeUniqueAppend:
+		merged := slices.Clone(dl)
+		for _, e := range sl {
+			if !slices.ContainsFunc(merged, func(m any) bool { return reflect.DeepEqual(m, e) }) {
+				merged = append(merged, e)
+			}
+		}
+		return merged
+	default:
+		return higher
+	}
+}
+
+// toAnySlice converts a slice of any element type to []any.
+func toAnySlice(val any) ([]any, bool) {
+	if s, ok := val.([]any); ok {
+		return s, true
+	}
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Slice {
+		return nil, false
+	}
+	s := make([]any, rv.Len())
+	for i := range s {
+		s[i] = rv.Index(i).Interface()
+	}
+	return s, true
+}
+
+// mergeFlagValues merges the values of the changed slice flags marked by MarkFlagMergeStrategy into the config values.
+// The merged value is set to viper as an override, which is still reported as the flag by ConfigSources.
+func mergeFlagValues(v *viper.Viper, flags *pflag.FlagSet, config map[string]any, r *redactor) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		names, ok := f.Annotations[mergeStrategyAnnotation]
+		if !ok || len(names) == 0 || !f.Changed || err != nil {
+			return
+		}
+		sv, ok := f.Value.(pflag.SliceValue)
+		if !ok {
+			return
+		}
+		var strategy MergeStrategy
+		if strategy, err = ParseMergeStrategy(names[0]); err != nil {
+			return
+		}
+		configVal, _ := configValueAt(config, f.Name)
+		merged := mergeConfigValue(configVal, sv.GetSlice(), strategy)
+		v.Set(f.Name, merged)
+		logger.Debug(fmt.Sprintf("%s the flag %s to the config value: %v", strategy, f.Name, r.redact(f.Name, merged)))
+	})
+	return err
+}
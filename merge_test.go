@@ -0,0 +1,97 @@
+package cobrax
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestMergeConfigValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		lower    any
+		higher   any
+		strategy MergeStrategy
+		want     any
+	}{
+		{"deep replaces lists", []any{"a", "b"}, []any{"c"}, MergeDeep, []any{"c"}},
+		{"replace", []any{"a", "b"}, []any{"c"}, MergeReplace, []any{"c"}},
+		{"append", []any{"a", "b"}, []any{"b", "c"}, MergeAppend, []any{"a", "b", "b", "c"}},
+		{"prepend", []any{"a", "b"}, []any{"c"}, MergePrepend, []any{"c", "a", "b"}},
+		{"unique append", []any{"a", "b"}, []any{"b", "c", "c"}, MergeUniqueAppend, []any{"a", "b", "c"}},
+		{"typed slices", []string{"a"}, []any{"b"}, MergeAppend, []any{"a", "b"}},
+		{"append to scalar", "a", []any{"b"}, MergeAppend, []any{"b"}},
+		{"append scalar", []any{"a"}, "b", MergeAppend, "b"},
+		{"append to unset", nil, []any{"b"}, MergeAppend, []any{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeConfigValue(tt.lower, tt.higher, tt.strategy); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeConfigValue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeConfigMapsWith(t *testing.T) {
+	dst := map[string]any{
+		"db":      map[string]any{"host": "localhost", "port": 5432},
+		"labels":  map[string]any{"a": "1"},
+		"exclude": []any{"vendor"},
+	}
+	src := map[string]any{
+		"db":      map[string]any{"host": "db"},
+		"labels":  map[string]any{"b": "2"},
+		"exclude": []any{"tmp"},
+	}
+	mergeConfigMapsWith(dst, src, "", map[string]MergeStrategy{"labels": MergeReplace, "exclude": MergeAppend})
+	want := map[string]any{
+		"db":      map[string]any{"host": "db", "port": 5432},
+		"labels":  map[string]any{"b": "2"},
+		"exclude": []any{"vendor", "tmp"},
+	}
+	if !reflect.DeepEqual(dst, want) {
+		t.Errorf("merged = %v, want %v", dst, want)
+	}
+}
+
+func TestParseMergeStrategy(t *testing.T) {
+	for _, s := range []MergeStrategy{MergeDeep, MergeReplace, MergeAppend, MergePrepend, MergeUniqueAppend} {
+		got, err := ParseMergeStrategy(s.String())
+		if err != nil || got != s {
+			t.Errorf("ParseMergeStrategy(%q) = %v, %v, want %v", s.String(), got, err, s)
+		}
+	}
+	if _, err := ParseMergeStrategy("merge"); err == nil {
+		t.Error("ParseMergeStrategy(\"merge\") succeeded, want an error")
+	}
+}
+
+func TestBindConfigsMergeStrategy(t *testing.T) {
+	flags := pflag.NewFlagSet("app", pflag.ContinueOnError)
+	flags.StringSlice("exclude", nil, "")
+	if err := MarkFlagMergeStrategy(flags, "exclude", MergeUniqueAppend); err != nil {
+		t.Fatal(err)
+	}
+	if err := flags.Parse([]string{"--exclude", "tmp,build"}); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := bindTestConfig(t, map[string]string{
+		"/home/u/.app.yaml": "exclude: [vendor]\nserver:\n  host: localhost\n  port: 80\n",
+		"/work/.app.yaml":   "exclude: [tmp]\nserver:\n  port: 8080\n",
+	}, nil, WithFlags(flags), WithMergeStrategy("exclude", MergeAppend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.GetStringSlice("exclude"), []string{"vendor", "tmp", "build"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("exclude = %v, want %v", got, want)
+	}
+	if got := v.GetString("server.host"); got != "localhost" {
+		t.Errorf("server.host = %q, want %q", got, "localhost")
+	}
+	if got := v.GetInt("server.port"); got != 8080 {
+		t.Errorf("server.port = %d, want %d", got, 8080)
+	}
+}
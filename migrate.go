@@ -0,0 +1,160 @@
+package cobrax
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// keyMigration is a renamed key, or a removed key when newKey is empty.
+type keyMigration struct {
+	oldKey  string
+	newKey  string
+	message string
+}
+
+func (km keyMigration) describe(prefix string) string {
+	if km.newKey == "" {
+		if km.message == "" {
+			return fmt.Sprintf("%s%s (removed)", prefix, km.oldKey)
+		}
+		return fmt.Sprintf("%s%s (removed: %s)", prefix, km.oldKey, km.message)
+	}
+	return fmt.Sprintf("%s%s (renamed to %s%s)", prefix, km.oldKey, prefix, km.newKey)
+}
+
+// migrateKeys applies the renamed and removed keys to the config read from a file, and returns the descriptions
+// of the deprecated keys found. Keys are migrated in the sections of migratedSections only, so that the keys of
+// map values (e.g. stringToString flags) are left as they are.
+// When both the old and new keys are set, the value of the new key is kept.
+func migrateKeys(opt *ConfigOptions, m map[string]any) []string {
+	if len(opt.keyMigrations) == 0 {
+		return nil
+	}
+	deprecated := make([]string, 0)
+	for _, section := range migratedSections(opt, m) {
+		sub, prefix := m, ""
+		if section != "" {
+			if sub = configMapAt(m, section); sub == nil {
+				continue
+			}
+			prefix = section + "."
+		}
+		for _, km := range opt.keyMigrations {
+			val, ok := configValueAt(sub, km.oldKey)
+			if !ok {
+				continue
+			}
+			deleteConfigValueAt(sub, km.oldKey)
+			if _, exists := configValueAt(sub, km.newKey); km.newKey != "" && !exists {
+				setConfigValueAt(sub, km.newKey, val)
+			}
+			deprecated = append(deprecated, km.describe(prefix))
+		}
+	}
+	slices.SortFunc(deprecated, strings.Compare)
+	return deprecated
+}
+
+// migratedSections returns the dotted keys of the sections whose keys are migrated: the top level (""),
+// the sections of the commands of the tree given by WithOverrideByCommand or WithStrict, the profiles,
+// and the sections of the commands in the profiles.
+func migratedSections(opt *ConfigOptions, m map[string]any) []string {
+	commands := make([]string, 0)
+	root := opt.commandRoot
+	if root == nil {
+		root = opt.strictRoot
+	}
+	if root != nil {
+		for key := range commandSectionKeys(root) {
+			commands = append(commands, key)
+		}
+		slices.Sort(commands)
+	}
+	sections := append([]string{""}, commands...)
+	profiles, _ := m[ProfilesKey].(map[string]any)
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		profile := ProfilesKey + "." + name
+		sections = append(sections, profile)
+		for _, key := range commands {
+			sections = append(sections, profile+"."+key)
+		}
+	}
+	return sections
+}
+
+// ConfigVersionKey is the config key of the version of the config file.
+const ConfigVersionKey = "config_version"
+
+// ConfigMigration migrates a config read from a file to the next version.
+type ConfigMigration func(map[string]any) (map[string]any, error)
+
+var ErrConfigVersionTooNew = errors.New("config version is newer than supported")
+
+// migrateConfigVersion runs the migrations from the version of the config file to the current version,
+// and returns the descriptions of the migrations run. The config_version key is removed from the config.
+func migrateConfigVersion(opt *ConfigOptions, path string, m map[string]any) (map[string]any, []string, error) {
+	if len(opt.versionMigrations) == 0 {
+		return m, nil, nil
+	}
+	version, err := configVersion(m[ConfigVersionKey])
+	if err == nil && version < 0 {
+		err = fmt.Errorf("invalid %s: %d", ConfigVersionKey, version)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	delete(m, ConfigVersionKey)
+	current := len(opt.versionMigrations)
+	if version > current {
+		return nil, nil, fmt.Errorf("%w: %s: %s %d, supported up to %d", ErrConfigVersionTooNew, path, ConfigVersionKey, version, current)
+	}
+
+	migrated := make([]string, 0, current-version)
+	for v := version; v < current; v++ {
+		if m, err = opt.versionMigrations[v](m); err != nil {
+			return nil, nil, fmt.Errorf("%s: failed to migrate %s %d to %d: %w", path, ConfigVersionKey, v, v+1, err)
+		}
+		if m == nil {
+			m = make(map[string]any)
+		}
+		migrated = append(migrated, fmt.Sprintf("%s %d to %d", ConfigVersionKey, v, v+1))
+		logger.Debug(fmt.Sprintf("migrated config file %s from %s %d to %d", path, ConfigVersionKey, v, v+1))
+	}
+	return m, migrated, nil
+}
+
+// stampConfigVersion sets the current version to the config written to a file, if the versions are enabled.
+func stampConfigVersion(opt *ConfigOptions, m map[string]any) {
+	if len(opt.versionMigrations) > 0 {
+		m[ConfigVersionKey] = len(opt.versionMigrations)
+	}
+}
+
+func configVersion(val any) (int, error) {
+	switch v := val.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(v); err == nil {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid %s: %v", ConfigVersionKey, val)
+}
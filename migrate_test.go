@@ -0,0 +1,143 @@
+package cobrax
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestKeyMigrations(t *testing.T) {
+	root := &cobra.Command{Use: "app"}
+	serve := &cobra.Command{Use: "serve"}
+	root.AddCommand(serve)
+
+	tests := []struct {
+		name    string
+		content string
+		opts    []ConfigOption
+		key     string
+		want    any
+	}{
+		{"renamed", "name: a", nil, "title", "a"},
+		{"old key removed", "name: a", nil, "name", nil},
+		{"new key kept", "name: a\ntitle: b", nil, "title", "b"},
+		{"removed", "old: x", nil, "old", nil},
+		{"map value", "labels:\n  name: keep", nil, "labels", map[string]any{"name": "keep"}},
+		{"profile", "profiles:\n  dev:\n    name: p", []ConfigOption{WithProfile("dev")}, "title", "p"},
+		{"command section", "serve:\n  name: s", []ConfigOption{WithOverrideByCommand(serve)}, "title", "s"},
+		{"command section in profile", "profiles:\n  dev:\n    serve:\n      name: ps", []ConfigOption{WithProfile("dev"), WithOverrideByCommand(serve)}, "title", "ps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ConfigOption{WithRenamedKey("name", "title"), WithRemovedKey("old", "no longer used")}, tt.opts...)
+			v, err := bindLocalConfig(t, tt.content, nil, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Get(tt.key); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateKeysDescriptions(t *testing.T) {
+	root := &cobra.Command{Use: "app"}
+	root.AddCommand(&cobra.Command{Use: "serve"})
+	opt := newConfigOptions("app", WithRenamedKey("name", "title"), WithRemovedKey("old", "no longer used"), WithStrict(root))
+	m := map[string]any{
+		"name":     "a",
+		"labels":   map[string]any{"name": "keep"},
+		"serve":    map[string]any{"old": "x"},
+		"profiles": map[string]any{"dev": map[string]any{"name": "p"}},
+	}
+	got := migrateKeys(opt, m)
+	want := []string{"name (renamed to title)", "profiles.dev.name (renamed to profiles.dev.title)", "serve.old (removed: no longer used)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("migrateKeys = %q, want %q", got, want)
+	}
+}
+
+func testConfigMigrations() []ConfigMigration {
+	return []ConfigMigration{
+		func(m map[string]any) (map[string]any, error) {
+			if host, ok := m["host"]; ok {
+				delete(m, "host")
+				m["server"] = map[string]any{"host": host}
+			}
+			return m, nil
+		},
+		func(m map[string]any) (map[string]any, error) {
+			if _, ok := m["fail"]; ok {
+				return nil, errors.New("cannot migrate fail")
+			}
+			m["migrated"] = true
+			return m, nil
+		},
+	}
+}
+
+func TestConfigVersionMigrations(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		key   string
+		want  any
+	}{
+		{"version 0", map[string]string{"/work/.app.yaml": "host: a\n"}, "server.host", "a"},
+		{"all migrations run", map[string]string{"/work/.app.yaml": "host: a\n"}, "migrated", true},
+		{"version 1", map[string]string{"/work/.app.yaml": "config_version: 1\nhost: a\n"}, "host", "a"},
+		{"current version", map[string]string{"/work/.app.yaml": "config_version: 2\n"}, "migrated", nil},
+		{"version as string", map[string]string{"/work/.app.yaml": "config_version: \"2\"\n"}, "migrated", nil},
+		{"version in JSON", map[string]string{"/work/.app.json": `{"config_version": 2}`}, "migrated", nil},
+		{"version key removed", map[string]string{"/work/.app.yaml": "config_version: 2\n"}, ConfigVersionKey, nil},
+		{
+			"included file migrated by its version",
+			map[string]string{"/work/.app.yaml": "config_version: 2\ninclude: [old.yaml]\n", "/work/old.yaml": "host: a\n"},
+			"server.host", "a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := bindTestConfig(t, tt.files, nil, WithConfigMigrations(testConfigMigrations()...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Get(tt.key); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigVersionMigrationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+		wantMsg string
+	}{
+		{"too new", "config_version: 3\n", ErrConfigVersionTooNew, "config_version 3, supported up to 2"},
+		{"negative", "config_version: -1\n", nil, "invalid config_version: -1"},
+		{"fraction", "config_version: 1.5\n", nil, "invalid config_version: 1.5"},
+		{"not a number", "config_version: one\n", nil, "invalid config_version: one"},
+		{"failed migration", "config_version: 1\nfail: true\n", nil, "failed to migrate config_version 1 to 2: cannot migrate fail"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bindLocalConfig(t, tt.content, nil, WithConfigMigrations(testConfigMigrations()...), WithConfigFileName("/work/.app.yaml"))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
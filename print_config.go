@@ -2,8 +2,10 @@ package cobrax
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
 	"io"
+	"slices"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/spf13/cobra"
@@ -13,42 +15,78 @@ import (
 
 var format PrintConfigFormat
 
-func PrintConfigCmd(name string) *cobra.Command {
+// PrintConfigCmd returns the command printing a configuration file with the defaults of the flags.
+// With WithConfigMigrations, the file has the current config_version.
+// The flags marked by MarkFlagSensitive, and the sensitive ones given on the command line, are left out of the file
+// instead of being written as "****", which would be read back as the value.
+func PrintConfigCmd(name string, opts ...ConfigOption) *cobra.Command {
 	genConfCmd := &cobra.Command{}
 	genConfCmd.Use = name
 	genConfCmd.Short = "Generate configuration file"
 	genConfCmd.Args = cobra.NoArgs
 	genConfCmd.RunE = func(cmd *cobra.Command, _ []string) error {
-		return PrintConfig(cmd.OutOrStdout(), GetFlags(cmd.Root()), format)
+		opt := newConfigOptions(cmd.Root().Name(), opts...)
+		r := newRedactor(opt, cmd.Root())
+		m := flagsConfigMap(cmd.Root(), func(key string, f *pflag.Flag) bool {
+			omit := (isSensitiveFlag(f) || f.Changed) && r.shouldRedact(key)
+			return !omit
+		})
+		stampConfigVersion(opt, m)
+		return PrintConfig(cmd.OutOrStdout(), m, format)
 	}
 
-	genConfCmd.Flags().Var(&format, "format", "The output format {toml|yaml|json}")
+	genConfCmd.Flags().Var(&format, "format", "The output format {"+strings.Join(printConfigFormats(), "|")+"}")
 
 	return genConfCmd
 }
 
 func GetFlags(cmd *cobra.Command) map[string]any {
+	return flagsConfigMap(cmd, func(string, *pflag.Flag) bool { return true })
+}
+
+// flagsConfigMap returns the values of the flags in the tree of GetFlags for which keep returns true with the dotted key.
+func flagsConfigMap(cmd *cobra.Command, keep func(key string, f *pflag.Flag) bool) map[string]any {
 	m := make(map[string]any)
+	visitConfigFlags(cmd, nil, func(path []string, f *pflag.Flag) {
+		if !keep(strings.ToLower(strings.Join(append(slices.Clip(path), f.Name), ".")), f) {
+			return
+		}
+		sub := m
+		for _, name := range path {
+			child, ok := sub[name].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				sub[name] = child
+			}
+			sub = child
+		}
+		sub[f.Name] = f.Value.String()
+	})
+	return m
+}
+
+// visitConfigFlags calls fn with the names of the commands under cmd and the flag, for each flag in the tree of GetFlags.
+func visitConfigFlags(cmd *cobra.Command, path []string, fn func(path []string, f *pflag.Flag)) {
 	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
 		if f.Deprecated != "" || f.Hidden || f.Name == "help" || f.Name == "version" {
 			return
 		}
-		m[f.Name] = f.Value.String()
+		fn(path, f)
 	})
-
 	for _, c := range cmd.Commands() {
 		if c.Deprecated != "" || c.Hidden {
 			continue
 		}
-		child := GetFlags(c)
-		if len(child) > 0 {
-			m[c.Name()] = child
-		}
+		visitConfigFlags(c, append(slices.Clip(path), c.Name()), fn)
 	}
-	return m
 }
 
+// PrintConfig writes the config as it is, so that it can be read back. Sensitive values are not redacted.
 func PrintConfig(w io.Writer, m map[string]any, format PrintConfigFormat) error {
+	return encodeConfig(w, m, format)
+}
+
+func encodeConfig(w io.Writer, m map[string]any, format PrintConfigFormat) error {
 	switch format {
 	case YAML:
 		return yaml.NewEncoder(w).Encode(m)
@@ -58,8 +96,19 @@ func PrintConfig(w io.Writer, m map[string]any, format PrintConfigFormat) error
 		enc := json.NewEncoder(w)
 		enc.SetIndent("", "  ")
 		return enc.Encode(m)
+	case "":
+		return nil
 	}
-	return nil
+	codec, ok := lookupConfigCodec(string(format))
+	if !ok {
+		return fmt.Errorf("unsupported config format: %q", format)
+	}
+	b, err := codec.Encode(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
 }
 
 type PrintConfigFormat string
@@ -68,8 +117,20 @@ const (
 	YAML PrintConfigFormat = "yaml"
 	JSON PrintConfigFormat = "json"
 	TOML PrintConfigFormat = "toml"
+
+	INI        PrintConfigFormat = "ini"
+	HCL        PrintConfigFormat = "hcl"
+	DOTENV     PrintConfigFormat = "env"
+	PROPERTIES PrintConfigFormat = "properties"
+	JSONC      PrintConfigFormat = "jsonc"
+	JSON5      PrintConfigFormat = "json5"
 )
 
+// printConfigFormats returns the built-in formats followed by the extensions of the registered codecs.
+func printConfigFormats() []string {
+	return append([]string{string(YAML), string(JSON), string(TOML)}, configCodecExts()...)
+}
+
 // String is used both by fmt.Print and by Cobra in help text
 func (f *PrintConfigFormat) String() string {
 	return string(*f)
@@ -77,13 +138,12 @@ func (f *PrintConfigFormat) String() string {
 
 // Set must have pointer receiver so it doesn't change the value of a copy
 func (f *PrintConfigFormat) Set(v string) error {
-	switch v {
-	case "yaml", "json", "toml":
-		*f = PrintConfigFormat(v)
-		return nil
-	default:
-		return errors.New(`must be one of "yaml", "json", or "toml"`)
+	formats := printConfigFormats()
+	if !slices.Contains(formats, strings.ToLower(v)) {
+		return fmt.Errorf("must be one of %q", formats)
 	}
+	*f = PrintConfigFormat(strings.ToLower(v))
+	return nil
 }
 
 // Type is only used in help text
@@ -0,0 +1,47 @@
+package cobrax
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestPrintConfigCmdSensitiveFlags(t *testing.T) {
+	rootCmd := NewRoot(viper.New())
+	rootCmd.Use = "app"
+	rootCmd.Flags().String("github-token-file", "/etc/token", "")
+	rootCmd.PersistentFlags().String("api-token", "", "")
+	rootCmd.PersistentFlags().String("db-token", "", "")
+	rootCmd.Flags().String("pin", "0000", "")
+	if err := MarkFlagSensitive(rootCmd.Flags(), "pin"); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.RunE = func(*cobra.Command, []string) error { return nil }
+	genCmd := PrintConfigCmd("gen")
+	rootCmd.AddCommand(genCmd)
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetArgs([]string{"gen", "--format", "yaml", "--db-token", "secret"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	m, err := decodeConfig("yaml", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m["github-token-file"]; got != "/etc/token" {
+		t.Errorf("github-token-file = %v, want the default", got)
+	}
+	if got := m["api-token"]; got != "" {
+		t.Errorf("api-token = %v, want the empty default", got)
+	}
+	if _, ok := m["db-token"]; ok {
+		t.Errorf("db-token = %v, want the sensitive flag given on the command line left out", m["db-token"])
+	}
+	if _, ok := m["pin"]; ok {
+		t.Errorf("pin = %v, want the marked flag left out", m["pin"])
+	}
+}
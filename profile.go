@@ -0,0 +1,45 @@
+package cobrax
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ProfilesKey is the config key of the map from profile names to their config values.
+const ProfilesKey = "profiles"
+
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// applyProfile merges the selected profile over the base config, before the sub-config override.
+func applyProfile(loaded *loadedConfig, opt *ConfigOptions, sources *ConfigSources) error {
+	if opt.profile == "" {
+		return nil
+	}
+	profiles, _ := loaded.config[ProfilesKey].(map[string]any)
+	profile, ok := profiles[strings.ToLower(opt.profile)].(map[string]any)
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		if len(names) == 0 {
+			return fmt.Errorf("%w %q: no profiles are defined", ErrUnknownProfile, opt.profile)
+		}
+		return fmt.Errorf("%w %q: available profiles are %s", ErrUnknownProfile, opt.profile, strings.Join(names, ", "))
+	}
+	mergeConfigMapsWith(loaded.config, profile, "", opt.mergeStrategies)
+	sources.record(profile, Source{Kind: SourceProfile, Name: opt.profile})
+	logger.Info(fmt.Sprintf("apply profile: %s", opt.profile))
+	return nil
+}
+
+func profileEnvName(opt *ConfigOptions) string {
+	prefix := opt.envPrefix
+	if prefix == "" {
+		prefix = opt.rootCmdName
+	}
+	return EnvName(prefix, opt.profileFlagName)
+}
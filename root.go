@@ -3,6 +3,7 @@ package cobrax
 import (
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -19,9 +20,25 @@ func NewRootWithOption(v *viper.Viper, option RootFlagOption) *cobra.Command {
 	rootCmd.SetHelpCommand(&cobra.Command{Hidden: true})
 	rootCmd.SilenceUsage = true  // don't show help content when error occurred
 	rootCmd.SilenceErrors = true // Print error by own slog logger
+	rootCmd.SetFlagErrorFunc(redactFlagError)
+	// The names of the flags read by RootPersistentPreRunE
+	rootCmd.Annotations = make(map[string]string)
 
 	if option.Config.Name != "" {
 		rootCmd.PersistentFlags().StringP(option.Config.Name, option.Config.Shorthand, "", option.Config.Usage)
+		rootCmd.Annotations[configFlagAnnotation] = option.Config.Name
+	}
+	if option.ConfigFormat.Name != "" {
+		rootCmd.PersistentFlags().VarP(new(PrintConfigFormat), option.ConfigFormat.Name, option.ConfigFormat.Shorthand, option.ConfigFormat.Usage)
+		rootCmd.Annotations[configFormatFlagAnnotation] = option.ConfigFormat.Name
+	}
+	if option.Profile.Name != "" {
+		rootCmd.PersistentFlags().StringP(option.Profile.Name, option.Profile.Shorthand, "", option.Profile.Usage)
+		rootCmd.Annotations[profileFlagAnnotation] = option.Profile.Name
+	}
+	if option.ConfigKey.Name != "" {
+		rootCmd.PersistentFlags().StringP(option.ConfigKey.Name, option.ConfigKey.Shorthand, "", option.ConfigKey.Usage)
+		rootCmd.Annotations[configKeyFlagAnnotation] = option.ConfigKey.Name
 	}
 	if option.NoColor.Name != "" {
 		rootCmd.PersistentFlags().BoolP(option.NoColor.Name, option.NoColor.Shorthand, false, option.NoColor.Usage)
@@ -38,17 +55,38 @@ func NewRootWithOption(v *viper.Viper, option RootFlagOption) *cobra.Command {
 	if option.Verbose.Name != "" && option.Quiet.Name != "" {
 		rootCmd.MarkFlagsMutuallyExclusive(option.Verbose.Name, option.Quiet.Name)
 	}
+	if option.EnvPrefix != "" {
+		rootCmd.Annotations[envPrefixAnnotation] = option.EnvPrefix
+		helpFunc := rootCmd.HelpFunc()
+		rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+			setEnvUsage(cmd, option.EnvPrefix, option.Config.Name, option.ConfigFormat.Name)
+			helpFunc(cmd, args)
+		})
+	}
 
 	return rootCmd
 }
 
 type RootFlagOption struct {
-	Config  FlagOption
-	NoColor FlagOption
-	Verbose FlagOption
-	Quiet   FlagOption
+	Config       FlagOption
+	ConfigFormat FlagOption // Disabled by default, e.g. set DefaultConfigFormatFlagOption to enable it
+	Profile      FlagOption // Disabled by default, e.g. set DefaultProfileFlagOption to enable it
+	ConfigKey    FlagOption // Disabled by default, e.g. set DefaultConfigKeyFlagOption to enable it
+	NoColor      FlagOption
+	Verbose      FlagOption
+	Quiet        FlagOption
+	// EnvPrefix enables binding environment variables named EnvPrefix_FLAG_NAME. Empty disables it.
+	EnvPrefix string
 }
 
+const (
+	envPrefixAnnotation        = "cobrax_env_prefix"
+	configFlagAnnotation       = "cobrax_config_flag"
+	configFormatFlagAnnotation = "cobrax_config_format_flag"
+	profileFlagAnnotation      = "cobrax_profile_flag"
+	configKeyFlagAnnotation    = "cobrax_config_key_flag"
+)
+
 type FlagOption struct {
 	Name      string
 	Shorthand string
@@ -56,24 +94,63 @@ type FlagOption struct {
 }
 
 var DefaultRootFlagOption = RootFlagOption{
-	Config:  FlagOption{Name: "config", Shorthand: "", Usage: "configuration `filename`"},
+	Config:  FlagOption{Name: "config", Shorthand: "", Usage: "configuration `filename` (\"-\" to read from stdin)"},
 	NoColor: FlagOption{Name: "no-color", Shorthand: "", Usage: "disable colorized output"},
 	Verbose: FlagOption{Name: "verbose", Shorthand: "v", Usage: "More output per occurrence. (e.g. -vvv)"},
 	Quiet:   FlagOption{Name: "quiet", Shorthand: "q", Usage: "Silence all output"},
 }
 
-func RootPersistentPreRunE(cmd *cobra.Command, v *viper.Viper, _ afero.Fs, _ []string) error {
-	// Read config file
-	opts := []ConfigOption{WithConfigFileFlag(cmd, "config"), WithOverrideBy(cmd.Name())}
-	if err := BindConfigs(v, cmd.Root().Name(), opts...); err != nil {
-		return err
+// Options of the optional flags of the root command.
+var (
+	DefaultConfigFormatFlagOption = FlagOption{Name: "config-format", Shorthand: "", Usage: "configuration format read from stdin {toml|yaml|json|ini|hcl|env|properties|jsonc|json5}"}
+	DefaultProfileFlagOption      = FlagOption{Name: "profile", Shorthand: "", Usage: "configuration profile `name`"}
+	DefaultConfigKeyFlagOption    = FlagOption{Name: "config-key", Shorthand: "", Usage: "key `file` to decrypt encrypted configuration files"}
+)
+
+func RootPersistentPreRunE(cmd *cobra.Command, v *viper.Viper, fs afero.Fs, _ []string, options ...ConfigOption) error {
+	// Read config file and bind flags (flags of the command to be executed)
+	opts := append(rootConfigFlagOptions(cmd), WithOverrideByCommand(cmd), WithFs(fs), WithFlags(cmd.Flags()))
+	if prefix, ok := cmd.Root().Annotations[envPrefixAnnotation]; ok {
+		opts = append(opts, WithEnvPrefix(prefix))
 	}
-	// Bind flags (flags of the command to be executed)
-	if err := v.BindPFlags(cmd.Flags()); err != nil {
-		return err
+	opts = append(opts, options...)
+	if _, ok := cmd.Annotations[skipConfigAnnotation]; ok {
+		return nil
 	}
+	return BindConfigs(v, cmd.Root().Name(), opts...)
+}
 
-	logger.Debug("bind flags and config values")
-	logger.Debug(DebugViper(v))
-	return nil
+// rootConfigFlagOptions returns the options of the flags of the root command configured by RootFlagOption.
+// The flags are looked up in the persistent flags of the root, so that a flag of the same name defined by
+// a subcommand is not taken for them.
+func rootConfigFlagOptions(cmd *cobra.Command) []ConfigOption {
+	root := cmd.Root()
+	opts := make([]ConfigOption, 0, 4)
+	if f := rootConfigFlag(cmd, configFlagAnnotation); f != nil {
+		opts = append(opts, WithConfigFileFlag(root, f.Name))
+	}
+	if f := rootConfigFlag(cmd, configFormatFlagAnnotation); f != nil {
+		opts = append(opts, WithConfigFormatFlag(root, f.Name))
+	}
+	if f := rootConfigFlag(cmd, profileFlagAnnotation); f != nil {
+		opts = append(opts, WithProfileFlag(root, f.Name))
+	}
+	if f := rootConfigFlag(cmd, configKeyFlagAnnotation); f != nil {
+		opts = append(opts, WithConfigKeyFileFlag(root, f.Name))
+	}
+	return opts
+}
+
+// rootConfigFlag returns the persistent flag of the root command named by the annotation, or nil.
+// The config flag of a root command without the annotations, i.e. not created by NewRootWithOption, is "config".
+func rootConfigFlag(cmd *cobra.Command, annotation string) *pflag.Flag {
+	root := cmd.Root()
+	name := root.Annotations[annotation]
+	if root.Annotations == nil && annotation == configFlagAnnotation {
+		name = DefaultRootFlagOption.Config.Name
+	}
+	if name == "" {
+		return nil
+	}
+	return root.PersistentFlags().Lookup(name)
 }
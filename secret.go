@@ -0,0 +1,151 @@
+package cobrax
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	secretFileScheme = "file://"
+	secretEnvScheme  = "env://"
+	secretExecScheme = "exec://"
+)
+
+var ErrSecretExecDisabled = errors.New("exec:// secret references are disabled (see WithSecretExec)")
+
+// resolveSecrets replaces secret references in config values with the secrets and marks the keys as sensitive.
+// The references in the profiles and the sections of commands are resolved only after they are applied.
+//
+//	file:///run/secrets/token  the content of the file
+//	env://API_TOKEN            the value of the environment variable
+//	exec://pass show token     the output of the command, only with WithSecretExec
+//	\file:///srv/git/repo      the literal "file:///srv/git/repo"
+func resolveSecrets(loaded *loadedConfig, opt *ConfigOptions) error {
+	r := &secretResolver{opt: opt, redactor: loaded.redactor, inactive: inactiveConfigSections(opt), cache: make(map[string]string), resolved: make(map[string]bool)}
+	if err := r.resolveMap("", loaded.config); err != nil {
+		return err
+	}
+	loaded.secretKeys = r.resolved
+	return nil
+}
+
+type secretResolver struct {
+	opt      *ConfigOptions
+	redactor *redactor
+	inactive map[string]bool // sections left unresolved
+	cache    map[string]string
+	resolved map[string]bool // keys of the resolved references
+}
+
+func (r *secretResolver) resolveMap(prefix string, m map[string]any) error {
+	for k, val := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if r.inactive[key] {
+			continue
+		}
+		resolved, err := r.resolveValue(key, val)
+		if err != nil {
+			return err
+		}
+		m[k] = resolved
+	}
+	return nil
+}
+
+func (r *secretResolver) resolveValue(key string, val any) (any, error) {
+	switch v := val.(type) {
+	case map[string]any:
+		return v, r.resolveMap(key, v)
+	case []any:
+		resolved := make([]any, len(v))
+		for i, e := range v {
+			s, err := r.resolveValue(key, e)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = s
+		}
+		return resolved, nil
+	case string:
+		if unescaped, ok := unescapeSecretReference(v); ok {
+			return unescaped, nil
+		}
+		if !isSecretReference(v) {
+			return v, nil
+		}
+		secret, err := r.resolve(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve secret reference of %s: %w", key, err)
+		}
+		r.redactor.mark(key)
+		r.resolved[key] = true
+		return secret, nil
+	default:
+		return val, nil
+	}
+}
+
+func (r *secretResolver) resolve(ref string) (string, error) {
+	if secret, ok := r.cache[ref]; ok {
+		return secret, nil
+	}
+	var secret string
+	var err error
+	switch {
+	case strings.HasPrefix(ref, secretFileScheme):
+		var b []byte
+		b, err = afero.ReadFile(r.opt.fs, strings.TrimPrefix(ref, secretFileScheme))
+		secret = strings.TrimRight(string(b), "\r\n")
+	case strings.HasPrefix(ref, secretEnvScheme):
+		name := strings.TrimPrefix(ref, secretEnvScheme)
+		var ok bool
+		if secret, ok = r.opt.env.LookupEnv(name); !ok {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+	case strings.HasPrefix(ref, secretExecScheme):
+		secret, err = r.exec(strings.TrimPrefix(ref, secretExecScheme))
+	}
+	if err != nil {
+		return "", err
+	}
+	r.cache[ref] = secret
+	return secret, nil
+}
+
+func (r *secretResolver) exec(command string) (string, error) {
+	if !r.opt.secretExecEnabled {
+		return "", ErrSecretExecDisabled
+	}
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+	var stderr bytes.Buffer
+	c := exec.Command(args[0], args[1:]...)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+func isSecretReference(s string) bool {
+	return strings.HasPrefix(s, secretFileScheme) || strings.HasPrefix(s, secretEnvScheme) || strings.HasPrefix(s, secretExecScheme)
+}
+
+// unescapeSecretReference returns the value without the leading backslash if it is an escaped secret reference.
+func unescapeSecretReference(s string) (string, bool) {
+	if strings.HasPrefix(s, `\`) && isSecretReference(s[1:]) {
+		return s[1:], true
+	}
+	return s, false
+}
@@ -0,0 +1,85 @@
+package cobrax
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	files := map[string]string{
+		"/run/secrets/token": "s3cret\n",
+		"/work/.app.yaml":    "token: file:///run/secrets/token\napi-key: env://API_KEY\nrepo: \\file:///srv/git/repo\n",
+	}
+	v, err := bindTestConfig(t, files, map[string]string{"API_KEY": "k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"token": "s3cret", "api-key": "k", "repo": "file:///srv/git/repo"}
+	for key, val := range want {
+		if got := v.GetString(key); got != val {
+			t.Errorf("%s = %q, want %q", key, got, val)
+		}
+	}
+}
+
+func TestResolveSecretsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		opts    []ConfigOption
+		wantErr error
+	}{
+		{"missing file", "token: file:///nonexist", nil, nil},
+		{"unset env", "token: env://UNSET", nil, nil},
+		{"exec without WithSecretExec", "token: exec://echo x", nil, ErrSecretExecDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bindLocalConfig(t, tt.content, nil, tt.opts...)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveSecretsDisabled(t *testing.T) {
+	v, err := bindLocalConfig(t, "repo: file:///srv/git/repo", nil, WithSecretReferencesDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetString("repo"); got != "file:///srv/git/repo" {
+		t.Errorf("repo = %q, want the literal value", got)
+	}
+}
+
+func TestResolveSecretsOfInactiveSections(t *testing.T) {
+	root := &cobra.Command{Use: "app"}
+	serve := &cobra.Command{Use: "serve"}
+	root.AddCommand(serve, &cobra.Command{Use: "deploy"})
+	content := "profiles:\n  prod:\n    db-token: file:///run/secrets/prod\n" +
+		"deploy:\n  token: file:///nonexist\n" +
+		"serve:\n  token: file:///run/secrets/serve\n"
+	files := map[string]string{"/work/.app.yaml": content, "/run/secrets/serve": "s"}
+
+	v, err := bindTestConfig(t, files, nil, WithOverrideByCommand(serve))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetString("token"); got != "s" {
+		t.Errorf("token = %q, want the secret of the serve section", got)
+	}
+
+	// The references of the selected profile and the running command are resolved.
+	if _, err := bindTestConfig(t, files, nil, WithOverrideByCommand(serve), WithProfile("prod")); err == nil {
+		t.Error("expected an error of the selected profile")
+	}
+	if _, err := bindTestConfig(t, files, nil, WithOverrideByCommand(root.Commands()[0])); err == nil {
+		t.Error("expected an error of the deploy section")
+	}
+}
@@ -0,0 +1,155 @@
+package cobrax
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const redacted = "****"
+
+// SensitiveAnnotation is the annotation of pflag.Flag marking the value of the flag as sensitive.
+// MarkFlagSensitive sets it.
+const SensitiveAnnotation = "cobrax_sensitive"
+
+// DefaultSensitiveKeyPatterns are the patterns of the config keys treated as sensitive by default.
+var DefaultSensitiveKeyPatterns = []string{"*password*", "*passwd*", "*secret*", "*token*", "*api?key*", "*apikey*", "*private?key*", "*credential*"}
+
+// redactor decides which config values are printed as "****". Each bound config has its own in ConfigSources,
+// with the patterns of the options and the keys marked while loading it. A nil redactor uses
+// DefaultSensitiveKeyPatterns only.
+type redactor struct {
+	mu       sync.RWMutex
+	patterns []string
+	keys     map[string]bool // dotted keys
+	flags    map[string]bool // flag names
+	disabled bool
+}
+
+// newRedactor returns the redactor with the patterns of opt and the sensitive flags of the command tree of root.
+// Both opt and root may be nil.
+func newRedactor(opt *ConfigOptions, root *cobra.Command) *redactor {
+	r := &redactor{patterns: DefaultSensitiveKeyPatterns, keys: make(map[string]bool), flags: make(map[string]bool)}
+	if opt != nil {
+		r.patterns = opt.sensitivePatterns
+		r.disabled = opt.redactionDisabled
+	}
+	if root != nil {
+		r.markFlags(root)
+	}
+	return r
+}
+
+// MarkFlagSensitive marks the value of the flag as sensitive. The config keys configuring the flag,
+// the flag name with or without the sections of commands, are sensitive too.
+func MarkFlagSensitive(flags *pflag.FlagSet, name string) error {
+	return flags.SetAnnotation(name, SensitiveAnnotation, []string{"true"})
+}
+
+func isSensitiveFlag(f *pflag.Flag) bool {
+	_, ok := f.Annotations[SensitiveAnnotation]
+	return ok
+}
+
+// markFlag records the flag if it is marked by MarkFlagSensitive.
+func (r *redactor) markFlag(f *pflag.Flag) {
+	if r == nil || !isSensitiveFlag(f) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flags[strings.ToLower(f.Name)] = true
+}
+
+// markFlags records the flags marked by MarkFlagSensitive in the command tree.
+func (r *redactor) markFlags(cmd *cobra.Command) {
+	cmd.LocalFlags().VisitAll(r.markFlag)
+	for _, c := range cmd.Commands() {
+		r.markFlags(c)
+	}
+}
+
+// mark marks the dotted config key as sensitive, so that its value is not printed in clear text.
+func (r *redactor) mark(key string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[strings.ToLower(key)] = true
+}
+
+func (r *redactor) isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	patterns := DefaultSensitiveKeyPatterns
+	if r != nil {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		if r.keys[key] || r.flags[key[strings.LastIndex(key, ".")+1:]] {
+			return true
+		}
+		patterns = r.patterns
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRedact reports whether the value of the key is printed as "****".
+func (r *redactor) shouldRedact(key string) bool {
+	return r.isSensitive(key) && (r == nil || !r.disabled)
+}
+
+// redact returns the value to be printed for the key.
+func (r *redactor) redact(key string, val any) any {
+	if r.shouldRedact(key) {
+		return redacted
+	}
+	return val
+}
+
+// redactMap returns a copy of the nested map with the values of sensitive keys redacted.
+func (r *redactor) redactMap(prefix string, m map[string]any) map[string]any {
+	redactedMap := make(map[string]any, len(m))
+	for k, val := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if child, ok := val.(map[string]any); ok {
+			redactedMap[k] = r.redactMap(key, child)
+			continue
+		}
+		redactedMap[k] = r.redact(key, val)
+	}
+	return redactedMap
+}
+
+// flagArgumentError matches the error of pflag for an invalid flag value, e.g. `invalid argument "x" for "--port" flag`.
+var flagArgumentError = regexp.MustCompile(`^invalid argument (".*") for "(?:-\w, )?--([^"]+)" flag`)
+
+// redactFlagError replaces the value in the error of pflag when the flag of the command is sensitive.
+func redactFlagError(cmd *cobra.Command, err error) error {
+	m := flagArgumentError.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	r := newRedactor(nil, nil)
+	if f := cmd.Flags().Lookup(m[2]); f != nil {
+		r.markFlag(f)
+	}
+	if !r.isSensitive(m[2]) {
+		return err
+	}
+	// The value is quoted also in the wrapped error, e.g. `strconv.ParseInt: parsing "x": invalid syntax`.
+	return errors.New(strings.ReplaceAll(err.Error(), m[1], strconv.Quote(redacted)))
+}
@@ -0,0 +1,224 @@
+package cobrax
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// SourceKind is the kind of layer a config value came from.
+type SourceKind int
+
+const (
+	SourceDefault SourceKind = iota
+	SourceFile
+	SourceProfile
+	SourceOverride
+	SourceEnv
+	SourceFlag
+)
+
+func (k SourceKind) String() string {
+	switch k {
+	case SourceFile:
+		return "file"
+	case SourceProfile:
+		return "profile"
+	case SourceOverride:
+		return "override"
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	default:
+		return "default"
+	}
+}
+
+// Source is the layer a config value came from.
+// Name is the file path, the profile name, the sub-config key, the env var name or the flag name depending on Kind.
+type Source struct {
+	Kind SourceKind
+	Name string
+}
+
+func (s Source) String() string {
+	switch s.Kind {
+	case SourceDefault:
+		return s.Kind.String()
+	case SourceFlag:
+		return fmt.Sprintf("%s --%s", s.Kind, s.Name)
+	case SourceFile:
+		if s.Name == StdinConfigFile {
+			return fmt.Sprintf("%s (stdin)", s.Kind)
+		}
+		return fmt.Sprintf("%s %s", s.Kind, s.Name)
+	default:
+		return fmt.Sprintf("%s %s", s.Kind, s.Name)
+	}
+}
+
+// Candidate is a value of a key found in a layer.
+type Candidate struct {
+	Source Source
+	Value  any
+}
+
+// ConfigSources records which layer set each config key.
+type ConfigSources struct {
+	mu         sync.RWMutex
+	keys       map[string]Source
+	candidates map[string][]Candidate
+	envs       map[string][]string // env var names bound to each key, from the highest precedence
+	envPrefix  string
+	flags      *pflag.FlagSet
+	sensitive  *redactor
+}
+
+func newConfigSources() *ConfigSources {
+	return &ConfigSources{keys: make(map[string]Source), candidates: make(map[string][]Candidate)}
+}
+
+// Lookup returns the layer the effective value of the key came from.
+func (s *ConfigSources) Lookup(key string) Source {
+	key = strings.ToLower(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.flags != nil {
+		if f := s.flags.Lookup(key); f != nil && f.Changed {
+			return Source{Kind: SourceFlag, Name: f.Name}
+		}
+	}
+	if name, _, ok := lookupEnv(s.envs[key]); ok {
+		return Source{Kind: SourceEnv, Name: name}
+	}
+	if src, ok := s.keys[key]; ok {
+		return src
+	}
+	return Source{Kind: SourceDefault}
+}
+
+// Keys returns the keys set by any layer other than defaults.
+func (s *ConfigSources) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.keys))
+	for k := range s.keys {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Candidates returns every value of the key found across the layers, from the lowest precedence to the highest.
+// Values shadowed by a higher layer are included.
+func (s *ConfigSources) Candidates(key string) []Candidate {
+	key = strings.ToLower(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	candidates := make([]Candidate, 0, len(s.candidates[key])+2)
+	var f *pflag.Flag
+	if s.flags != nil {
+		f = s.flags.Lookup(key)
+	}
+	if f != nil {
+		candidates = append(candidates, Candidate{Source: Source{Kind: SourceDefault}, Value: f.DefValue})
+	}
+	candidates = append(candidates, s.candidates[key]...)
+	for i := len(s.envs[key]) - 1; i >= 0; i-- {
+		if name, val, ok := lookupEnv(s.envs[key][i : i+1]); ok {
+			candidates = append(candidates, Candidate{Source: Source{Kind: SourceEnv, Name: name}, Value: val})
+		}
+	}
+	if f != nil && f.Changed {
+		candidates = append(candidates, Candidate{Source: Source{Kind: SourceFlag, Name: f.Name}, Value: f.Value.String()})
+	}
+	return candidates
+}
+
+func (s *ConfigSources) record(m map[string]any, src Source) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flattenConfigMap("", m, func(key string, val any) {
+		s.keys[key] = src
+		s.candidates[key] = append(s.candidates[key], Candidate{Source: src, Value: val})
+	})
+}
+
+func (s *ConfigSources) setFlags(flags *pflag.FlagSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags = flags
+}
+
+func (s *ConfigSources) setRedactor(r *redactor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sensitive = r
+}
+
+// redactor returns the redactor of the config, or nil for the default one when s is nil or not bound yet.
+func (s *ConfigSources) redactor() *redactor {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sensitive
+}
+
+func (s *ConfigSources) setEnvs(prefix string, envs map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.envPrefix = prefix
+	s.envs = envs
+}
+
+// flattenConfigMap calls fn with the dotted key of each leaf value in m.
+func flattenConfigMap(prefix string, m map[string]any, fn func(key string, val any)) {
+	for k, val := range m {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if child, ok := val.(map[string]any); ok && len(child) > 0 {
+			flattenConfigMap(key, child, fn)
+			continue
+		}
+		fn(key, val)
+	}
+}
+
+// configSources is keyed by the address of viper instead of the pointer, so that it does not keep viper alive.
+// The entry is removed by the finalizer of viper.
+var (
+	configSourcesMu sync.Mutex
+	configSources   = make(map[uintptr]*ConfigSources)
+)
+
+// Sources returns the ConfigSources recorded by the last BindConfigs call on v, or nil if v has not been bound.
+func Sources(v *viper.Viper) *ConfigSources {
+	configSourcesMu.Lock()
+	defer configSourcesMu.Unlock()
+	return configSources[reflect.ValueOf(v).Pointer()]
+}
+
+// setConfigSources records the sources of v until v is garbage collected.
+// It sets the finalizer of v, so v must not have another finalizer.
+func setConfigSources(v *viper.Viper, s *ConfigSources) {
+	configSourcesMu.Lock()
+	defer configSourcesMu.Unlock()
+	key := reflect.ValueOf(v).Pointer()
+	if _, ok := configSources[key]; !ok {
+		runtime.SetFinalizer(v, func(*viper.Viper) {
+			configSourcesMu.Lock()
+			defer configSourcesMu.Unlock()
+			delete(configSources, key)
+		})
+	}
+	configSources[key] = s
+}
@@ -0,0 +1,53 @@
+package cobrax
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestSourcesAreReleasedWithViper(t *testing.T) {
+	count := func() int {
+		configSourcesMu.Lock()
+		defer configSourcesMu.Unlock()
+		return len(configSources)
+	}
+	before := count()
+	func() {
+		for i := 0; i < 10; i++ {
+			setConfigSources(viper.New(), newConfigSources())
+		}
+	}()
+	for i := 0; i < 50 && count() > before; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := count(); got > before {
+		t.Errorf("%d sources of collected vipers are left", got-before)
+	}
+}
+
+func TestSourcesLookup(t *testing.T) {
+	v, err := bindTestConfig(t, map[string]string{
+		"/home/u/.app.yaml": "name: home\nport: 80\n",
+		"/work/.app.yaml":   "name: local\n",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := Sources(v)
+	if got := sources.Lookup("name"); got != (Source{Kind: SourceFile, Name: "/work/.app.yaml"}) {
+		t.Errorf("Lookup(name) = %v", got)
+	}
+	if got := sources.Lookup("port"); got != (Source{Kind: SourceFile, Name: "/home/u/.app.yaml"}) {
+		t.Errorf("Lookup(port) = %v", got)
+	}
+	if got := sources.Lookup("unset"); got.Kind != SourceDefault {
+		t.Errorf("Lookup(unset) = %v", got)
+	}
+	if got := len(sources.Candidates("name")); got != 2 {
+		t.Errorf("Candidates(name) has %d values, want 2", got)
+	}
+}
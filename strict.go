@@ -0,0 +1,126 @@
+package cobrax
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var ErrUnknownConfigKey = errors.New("unknown config key")
+
+// checkUnknownKeys checks the keys of every loaded file against the flags of the command tree.
+func checkUnknownKeys(loaded *loadedConfig, opt *ConfigOptions) error {
+	if opt.strictRoot == nil {
+		return nil
+	}
+	known := knownConfigKeys(opt.strictRoot)
+	errs := make([]error, 0)
+	for _, layer := range loaded.layers {
+		keys := make([]string, 0)
+		flattenConfigMap("", layer.config, func(key string, _ any) {
+			if !isKnownConfigKey(known, key) {
+				keys = append(keys, key)
+			}
+		})
+		slices.SortFunc(keys, sortConfigKey)
+		for _, key := range keys {
+			err := fmt.Errorf("%w: %s: %s", ErrUnknownConfigKey, layer.path, key)
+			if suggestion := suggestConfigKey(known, key); suggestion != "" {
+				err = fmt.Errorf("%w (did you mean %q?)", err, suggestion)
+			}
+			if opt.strictWarnOnly {
+				logger.Warn(err.Error())
+				continue
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// knownConfigKeys returns the keys of the flags of the command tree in the structure of GetFlags.
+// Every flag name is also known at the top level, since top-level values apply to any command.
+func knownConfigKeys(root *cobra.Command) map[string]bool {
+	known := make(map[string]bool)
+	var walk func(cmd *cobra.Command, prefix string)
+	walk = func(cmd *cobra.Command, prefix string) {
+		add := func(f *pflag.Flag) {
+			name := strings.ToLower(f.Name)
+			known[prefix+name] = true
+			known[name] = true
+		}
+		cmd.LocalFlags().VisitAll(add)
+		cmd.InheritedFlags().VisitAll(add)
+		for _, c := range cmd.Commands() {
+			walk(c, prefix+strings.ToLower(c.Name())+".")
+		}
+	}
+	walk(root, "")
+	return known
+}
+
+func isKnownConfigKey(known map[string]bool, key string) bool {
+	key = profileValueKey(key)
+	// A key under a known key is a value of a map flag (e.g. stringToString)
+	for k := key; ; {
+		if known[k] {
+			return true
+		}
+		i := strings.LastIndex(k, ".")
+		if i < 0 {
+			return false
+		}
+		k = k[:i]
+	}
+}
+
+// profileValueKey returns the key of a value of a profile as the top-level key, since it is checked as such.
+func profileValueKey(key string) string {
+	if rest, ok := strings.CutPrefix(key, ProfilesKey+"."); ok {
+		if _, rest, ok = strings.Cut(rest, "."); ok {
+			return rest
+		}
+	}
+	return key
+}
+
+// suggestConfigKey returns the known key closest to the key, or empty if none is close enough.
+// A key of a profile is compared as the top-level key, and the suggestion is in the same profile.
+func suggestConfigKey(known map[string]bool, key string) string {
+	name := profileValueKey(key)
+	best, bestDist := "", max(2, len(name)/3)+1
+	for k := range known {
+		if d := levenshtein(name, k); d < bestDist || (d == bestDist && k < best) {
+			best, bestDist = k, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return strings.TrimSuffix(key, name) + best
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
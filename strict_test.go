@@ -0,0 +1,66 @@
+package cobrax
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func newStrictTestRoot() *cobra.Command {
+	root := &cobra.Command{Use: "app"}
+	root.Flags().Int("port", 0, "")
+	root.Flags().StringToString("labels", nil, "")
+	serve := &cobra.Command{Use: "serve"}
+	serve.Flags().String("listen", "", "")
+	root.AddCommand(serve)
+	return root
+}
+
+func TestStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantMsg string // empty when the config is valid
+	}{
+		{"known keys", "port: 80\nserve:\n  listen: :80\n", ""},
+		{"flag of subcommand at the top level", "listen: :80\n", ""},
+		{"values of map flag", "labels:\n  a: b\n", ""},
+		{"profile", "profiles:\n  dev:\n    port: 1\n", ""},
+		{"typo", "prot: 80\n", `/work/.app.yaml: prot (did you mean "port"?)`},
+		{"typo in section", "serve:\n  lisen: :80\n", `/work/.app.yaml: serve.lisen (did you mean "serve.listen"?)`},
+		{"typo in profile", "profiles:\n  dev:\n    prot: 1\n", `profiles.dev.prot (did you mean "profiles.dev.port"?)`},
+		{"no suggestion", "completely-unrelated: 1\n", "/work/.app.yaml: completely-unrelated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bindLocalConfig(t, tt.content, nil, WithStrict(newStrictTestRoot()))
+			if tt.wantMsg == "" {
+				if err != nil {
+					t.Errorf("err = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnknownConfigKey) {
+				t.Fatalf("err = %v, want %v", err, ErrUnknownConfigKey)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantMsg)
+			}
+			if strings.HasSuffix(tt.wantMsg, "unrelated") && strings.Contains(err.Error(), "did you mean") {
+				t.Errorf("err = %v, want no suggestion", err)
+			}
+		})
+	}
+}
+
+func TestStrictWarn(t *testing.T) {
+	v, err := bindLocalConfig(t, "prot: 80\n", nil, WithStrictWarn(newStrictTestRoot()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetInt("prot"); got != 80 {
+		t.Errorf("prot = %d, want the unknown key kept", got)
+	}
+}
@@ -0,0 +1,258 @@
+package cobrax
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// ConfigChange is a change of the value of a config key made by reloading config files.
+type ConfigChange struct {
+	Key string
+	Old any // nil when the key is added
+	New any // nil when the key is removed
+}
+
+// ConfigWatcher reloads config files when any of the loaded files changes.
+type ConfigWatcher struct {
+	v       *viper.Viper
+	opt     *ConfigOptions
+	watcher *fsnotify.Watcher
+
+	reloadMu sync.Mutex
+	files    []string
+	dirs     []string
+
+	configMu sync.RWMutex // Held while viper is being replaced by Reload
+
+	callbackMu sync.RWMutex
+	onChange   []func([]ConfigChange)
+	onError    []func(error)
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// WatchConfigs binds config files like BindConfigs and reloads them when any of the loaded files changes.
+// Reloading runs the same discovery, merge and sub-config override. When it fails, the error is reported
+// to the OnError callbacks and the previous config is kept.
+//
+// Files are watched on the OS filesystem. Callbacks are called on the goroutine of the watcher.
+// Viper is reset and bound again while reloading, so other goroutines must read it between RLock and RUnlock.
+func WatchConfigs(v *viper.Viper, rootCmdName string, opts ...ConfigOption) (*ConfigWatcher, error) {
+	opt := newConfigOptions(rootCmdName, opts...)
+	sources := newConfigSources()
+	setConfigSources(v, sources)
+	loaded, err := loadConfigs(opt, sources)
+	if err != nil {
+		return nil, err
+	}
+	if err := bindLoadedConfig(v, opt, sources, loaded); err != nil {
+		return nil, err
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &ConfigWatcher{v: v, opt: opt, watcher: fw, done: make(chan struct{})}
+	w.watch(loaded.files)
+	go w.run()
+	return w, nil
+}
+
+// OnChange registers the callback called with the changed keys after config files are reloaded.
+func (w *ConfigWatcher) OnChange(fn func(changes []ConfigChange)) {
+	w.callbackMu.Lock()
+	defer w.callbackMu.Unlock()
+	w.onChange = append(w.onChange, fn)
+}
+
+// OnError registers the callback called when reloading config files fails.
+func (w *ConfigWatcher) OnError(fn func(err error)) {
+	w.callbackMu.Lock()
+	defer w.callbackMu.Unlock()
+	w.onError = append(w.onError, fn)
+}
+
+// RLock locks viper for reading, so that it is not reloaded until RUnlock is called.
+func (w *ConfigWatcher) RLock() {
+	w.configMu.RLock()
+}
+
+// RUnlock undoes a single RLock call.
+func (w *ConfigWatcher) RUnlock() {
+	w.configMu.RUnlock()
+}
+
+// Files returns the config files currently loaded and watched.
+func (w *ConfigWatcher) Files() []string {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	return slices.Clone(w.files)
+}
+
+// Close stops watching config files.
+func (w *ConfigWatcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.watcher.Close()
+	})
+	return err
+}
+
+// Reload reads config files again and calls the callbacks.
+// It is called automatically when a loaded file changes, but can also be called manually (e.g. on SIGHUP).
+func (w *ConfigWatcher) Reload() error {
+	w.reloadMu.Lock()
+	sources := newConfigSources()
+	loaded, err := loadConfigs(w.opt, sources)
+	if err == nil {
+		err = errors.Join(loaded.errs...)
+	}
+	if err != nil {
+		w.reloadMu.Unlock()
+		w.reportError(err)
+		return err
+	}
+
+	w.configMu.Lock()
+	old := snapshotConfig(w.v)
+	resetConfig(w.v)
+	setConfigSources(w.v, sources)
+	var changes []ConfigChange
+	if err = bindLoadedConfig(w.v, w.opt, sources, loaded); err == nil {
+		changes = diffConfig(old, snapshotConfig(w.v))
+	}
+	w.configMu.Unlock()
+	if err != nil {
+		w.reloadMu.Unlock()
+		w.reportError(err)
+		return err
+	}
+	w.watch(loaded.files)
+	w.reloadMu.Unlock()
+
+	logger.Info(fmt.Sprintf("reloaded config files: %d keys changed", len(changes)))
+	if len(changes) == 0 {
+		return nil
+	}
+	w.callbackMu.RLock()
+	defer w.callbackMu.RUnlock()
+	for _, fn := range w.onChange {
+		fn(changes)
+	}
+	return nil
+}
+
+func (w *ConfigWatcher) run() {
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !ev.Has(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) || !w.isLoaded(ev.Name) {
+				continue
+			}
+			logger.Debug(fmt.Sprintf("config file changed: %s", ev))
+			debounce = time.After(w.opt.reloadDebounce)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.reportError(err)
+		case <-debounce:
+			debounce = nil
+			_ = w.Reload() // error is reported to the callbacks
+		}
+	}
+}
+
+// watch replaces the watched files. Directories are watched so that files replaced by editors are detected.
+// It must be called with reloadMu held.
+func (w *ConfigWatcher) watch(files []string) {
+	w.files = make([]string, 0, len(files))
+	dirs := make([]string, 0, len(files))
+	for _, f := range files {
+		if f == StdinConfigFile {
+			continue
+		}
+		f = filepath.Clean(f)
+		w.files = append(w.files, f)
+		if dir := filepath.Dir(f); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, dir := range w.dirs {
+		if !slices.Contains(dirs, dir) {
+			_ = w.watcher.Remove(dir)
+		}
+	}
+	for _, dir := range dirs {
+		if slices.Contains(w.dirs, dir) {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			logger.Debug(err.Error())
+		}
+	}
+	w.dirs = dirs
+}
+
+func (w *ConfigWatcher) isLoaded(name string) bool {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	return slices.Contains(w.files, filepath.Clean(name))
+}
+
+func (w *ConfigWatcher) reportError(err error) {
+	logger.Warn(fmt.Sprintf("failed to reload config files: %v", err))
+	w.callbackMu.RLock()
+	defer w.callbackMu.RUnlock()
+	for _, fn := range w.onError {
+		fn(err)
+	}
+}
+
+// resetConfig clears the values read from config files, since viper has no API to remove them.
+// ReadConfig replaces the config with an empty map before decoding, so decoding the empty input is harmless.
+func resetConfig(v *viper.Viper) {
+	_ = v.ReadConfig(strings.NewReader(""))
+}
+
+func snapshotConfig(v *viper.Viper) map[string]any {
+	m := make(map[string]any)
+	for _, k := range v.AllKeys() {
+		m[k] = v.Get(k)
+	}
+	return m
+}
+
+func diffConfig(old, cur map[string]any) []ConfigChange {
+	changes := make([]ConfigChange, 0)
+	for k, o := range old {
+		if n, ok := cur[k]; !ok || !reflect.DeepEqual(o, n) {
+			changes = append(changes, ConfigChange{Key: k, Old: o, New: cur[k]})
+		}
+	}
+	for k, n := range cur {
+		if _, ok := old[k]; !ok {
+			changes = append(changes, ConfigChange{Key: k, New: n})
+		}
+	}
+	slices.SortFunc(changes, func(a, b ConfigChange) int { return sortConfigKey(a.Key, b.Key) })
+	return changes
+}
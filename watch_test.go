@@ -0,0 +1,67 @@
+package cobrax
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+func TestConfigWatcherReload(t *testing.T) {
+	fs := newTestFs(t, map[string]string{"/work/.app.yaml": "name: a\nport: 80\nold: x\n"})
+	v := viper.New()
+	w, err := WatchConfigs(v, "app", WithFs(fs), WithEnvironment(newTestEnvironment(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	var changes []ConfigChange
+	var errs []error
+	w.OnChange(func(c []ConfigChange) { changes = append(changes, c...) })
+	w.OnError(func(err error) { errs = append(errs, err) })
+
+	write := func(content string) {
+		t.Helper()
+		if err := afero.WriteFile(fs, "/work/.app.yaml", []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("name: b\nport: 80\nnew: y\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	want := []ConfigChange{{Key: "name", Old: "a", New: "b"}, {Key: "new", New: "y"}, {Key: "old", Old: "x"}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+	if v.IsSet("old") {
+		t.Error("old is still set after it is removed from the file")
+	}
+
+	// A broken file keeps the previous config.
+	changes = nil
+	write("name: [\n")
+	if err := w.Reload(); err == nil {
+		t.Error("expected an error of the broken file")
+	}
+	if len(errs) != 1 || len(changes) != 0 {
+		t.Errorf("OnError is called %d times and OnChange with %v, want only OnError once", len(errs), changes)
+	}
+	w.RLock()
+	got := v.GetString("name")
+	w.RUnlock()
+	if got != "b" {
+		t.Errorf("name = %q, want the previous value", got)
+	}
+
+	// Reloading without changes does not call OnChange.
+	write("name: b\nport: 80\nnew: y\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("changes = %v, want none", changes)
+	}
+}